@@ -0,0 +1,244 @@
+// A股交易日历
+
+package calendar
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// 交易时段 (距离0点的分钟数): 09:30~11:30, 13:00~15:00
+const (
+	MorningOpen    = 9*60 + 30
+	MorningClose   = 11*60 + 30
+	AfternoonOpen  = 13 * 60
+	AfternoonClose = 15 * 60
+)
+
+const dayLayout = "2006-01-02"
+
+//go:embed holidays.json
+var embedHolidays []byte
+
+// Location 交易所所在时区, 系统缺少时区数据时退化为固定的东八区
+var Location = loadLocation()
+
+var defaultCalendar = mustNewEmbedCalendar()
+
+// HolidayData 节假日数据文件格式, 年份 -> 休市日期 (周末之外), 例如: {"holidays": {"2022": ["01-03", "01-31"]}}
+type HolidayData struct {
+	Holidays map[string][]string `json:"holidays"`
+}
+
+// Calendar 沪深交易所交易日历, 周末及节假日休市
+// 节假日数据未覆盖的年份只排除周末
+type Calendar struct {
+	mu       sync.RWMutex
+	holidays map[string]bool // 2006-01-02 -> 是否休市
+	years    map[int]bool    // 有节假日数据的年份
+}
+
+// Session 单个交易时段
+type Session struct {
+	Open  time.Time
+	Close time.Time
+}
+
+func loadLocation() *time.Location {
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		return time.FixedZone("CST", 8*3600)
+	}
+	return loc
+}
+
+func mustNewEmbedCalendar() *Calendar {
+	c := NewCalendar()
+	if err := c.AddHolidayData(embedHolidays); err != nil {
+		panic(fmt.Sprintf("load embed holidays fail: %v", err))
+	}
+	return c
+}
+
+// NewCalendar 创建不包含任何节假日数据的日历
+func NewCalendar() *Calendar {
+	return &Calendar{
+		holidays: make(map[string]bool),
+		years:    make(map[int]bool),
+	}
+}
+
+// Default 获取内置节假日数据的默认日历
+func Default() *Calendar {
+	return defaultCalendar
+}
+
+// AddHolidayData 从json内容中加载节假日数据
+func (c *Calendar) AddHolidayData(content []byte) (err error) {
+	var data HolidayData
+	if err = json.Unmarshal(content, &data); err != nil {
+		return fmt.Errorf("unmarshal holidays fail: %v", err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for year, days := range data.Holidays {
+		for _, day := range days {
+			t, parseErr := time.Parse(dayLayout, fmt.Sprintf("%s-%s", year, day))
+			if parseErr != nil {
+				return fmt.Errorf("unexpect holiday: year=%s day=%s", year, day)
+			}
+			c.holidays[t.Format(dayLayout)] = true
+			c.years[t.Year()] = true
+		}
+	}
+	return
+}
+
+// LoadHolidays 从json文件中加载节假日数据, 用于补充内置数据未覆盖的年份
+func (c *Calendar) LoadHolidays(path string) (err error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	return c.AddHolidayData(content)
+}
+
+// IsCovered 指定年份是否有节假日数据
+func (c *Calendar) IsCovered(year int) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.years[year]
+}
+
+// IsTradingDay 判断某天是否为交易日 (按t所在时区的日期判断)
+func (c *Calendar) IsTradingDay(t time.Time) bool {
+	if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		return false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return !c.holidays[t.Format(dayLayout)]
+}
+
+// NextTradingDay 获取t之后的第一个交易日 (不包括t当天)
+func (c *Calendar) NextTradingDay(t time.Time) time.Time {
+	return c.AddTradingDays(t, 1)
+}
+
+// PrevTradingDay 获取t之前的最后一个交易日 (不包括t当天)
+func (c *Calendar) PrevTradingDay(t time.Time) time.Time {
+	return c.AddTradingDays(t, -1)
+}
+
+// AddTradingDays 获取t之后(n<0时为之前)的第n个交易日, 返回当天0点
+func (c *Calendar) AddTradingDays(t time.Time, n int) time.Time {
+	day := truncateDay(t)
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+	for n > 0 {
+		day = day.AddDate(0, 0, step)
+		if c.IsTradingDay(day) {
+			n--
+		}
+	}
+	return day
+}
+
+// TradingDaysBetween 统计[from, to]日期范围内的交易日数量, from晚于to时返回0
+func (c *Calendar) TradingDaysBetween(from, to time.Time) (count int) {
+	end := truncateDay(to)
+	for day := truncateDay(from); !day.After(end); day = day.AddDate(0, 0, 1) {
+		if c.IsTradingDay(day) {
+			count++
+		}
+	}
+	return
+}
+
+// Sessions 获取某天的交易时段, 非交易日返回空
+func (c *Calendar) Sessions(t time.Time) []Session {
+	if !c.IsTradingDay(t) {
+		return nil
+	}
+	day := truncateDay(t)
+	at := func(minutes int) time.Time {
+		return day.Add(time.Duration(minutes) * time.Minute)
+	}
+	return []Session{
+		{Open: at(MorningOpen), Close: at(MorningClose)},
+		{Open: at(AfternoonOpen), Close: at(AfternoonClose)},
+	}
+}
+
+// IsTradingTime 判断t是否处于交易时段内
+func (c *Calendar) IsTradingTime(t time.Time) bool {
+	for _, session := range c.Sessions(t) {
+		if !t.Before(session.Open) && !t.After(session.Close) {
+			return true
+		}
+	}
+	return false
+}
+
+// DayClose 获取某天的收盘时间
+func DayClose(t time.Time) time.Time {
+	return truncateDay(t).Add(AfternoonClose * time.Minute)
+}
+
+// 获取t所在时区当天的0点
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// ================ 默认日历 ===========
+
+// IsTradingDay 使用默认日历判断某天是否为交易日
+func IsTradingDay(t time.Time) bool {
+	return defaultCalendar.IsTradingDay(t)
+}
+
+// NextTradingDay 使用默认日历获取t之后的第一个交易日
+func NextTradingDay(t time.Time) time.Time {
+	return defaultCalendar.NextTradingDay(t)
+}
+
+// PrevTradingDay 使用默认日历获取t之前的最后一个交易日
+func PrevTradingDay(t time.Time) time.Time {
+	return defaultCalendar.PrevTradingDay(t)
+}
+
+// AddTradingDays 使用默认日历获取t之后的第n个交易日
+func AddTradingDays(t time.Time, n int) time.Time {
+	return defaultCalendar.AddTradingDays(t, n)
+}
+
+// TradingDaysBetween 使用默认日历统计日期范围内的交易日数量
+func TradingDaysBetween(from, to time.Time) int {
+	return defaultCalendar.TradingDaysBetween(from, to)
+}
+
+// Sessions 使用默认日历获取某天的交易时段
+func Sessions(t time.Time) []Session {
+	return defaultCalendar.Sessions(t)
+}
+
+// IsTradingTime 使用默认日历判断t是否处于交易时段内
+func IsTradingTime(t time.Time) bool {
+	return defaultCalendar.IsTradingTime(t)
+}
+
+// LoadHolidays 为默认日历补充节假日数据
+func LoadHolidays(path string) error {
+	return defaultCalendar.LoadHolidays(path)
+}
+
+// InLocation 将时间戳转换为交易所时区的时间
+func InLocation(timestamp int64) time.Time {
+	return time.Unix(timestamp, 0).In(Location)
+}
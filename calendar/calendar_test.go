@@ -0,0 +1,83 @@
+package calendar
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+)
+
+func day(desc string) time.Time {
+	t, _ := time.ParseInLocation(dayLayout, desc, Location)
+	return t
+}
+
+// 读取日线模拟数据中的日期
+func readMockDays(t *testing.T, path string) (days []string) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read mock data fail: err=%v", err)
+	}
+	var data struct {
+		KLines []struct {
+			TimeDesc string `json:"sj"`
+		} `json:"kLines"`
+	}
+	if err = json.Unmarshal(content, &data); err != nil {
+		t.Fatalf("unmarshal fail: err=%v", err)
+	}
+	for _, node := range data.KLines {
+		days = append(days, node.TimeDesc)
+	}
+	return
+}
+
+func TestTradingDaysWithMockData(t *testing.T) {
+	// 个股或基金停牌的日期不在数据中, 但数据中的日期都必须是交易日
+	for _, path := range []string{"../dao/mockdata/510500_1day.json", "../dao/mockdata/600036_1day.json"} {
+		for _, desc := range readMockDays(t, path) {
+			if !IsTradingDay(day(desc)) {
+				t.Errorf("%s should be trading day", desc)
+			}
+		}
+	}
+
+	// 513050没有停牌记录, 交易日与数据完全一致
+	days := readMockDays(t, "../dao/mockdata/513050_1day.json")
+	for i := 1; i < len(days); i++ {
+		if next := NextTradingDay(day(days[i-1])).Format(dayLayout); next != days[i] {
+			t.Errorf("unexpect next trading day of %s: got=%s expect=%s", days[i-1], next, days[i])
+		}
+	}
+	first, last := days[0], days[len(days)-1]
+	if n := TradingDaysBetween(day(first), day(last)); n != len(days) {
+		t.Errorf("unexpect trading days between %s and %s: got=%d expect=%d", first, last, n, len(days))
+	}
+}
+
+func TestCalendar(t *testing.T) {
+	if IsTradingDay(day("2022-10-01")) || IsTradingDay(day("2022-10-07")) || !IsTradingDay(day("2022-10-10")) {
+		t.Errorf("unexpect national day holidays")
+	}
+	if got := AddTradingDays(day("2022-09-29"), 2).Format(dayLayout); got != "2022-10-10" {
+		t.Errorf("unexpect add trading days: %s", got)
+	}
+	if got := PrevTradingDay(day("2022-10-10")).Format(dayLayout); got != "2022-09-30" {
+		t.Errorf("unexpect prev trading day: %s", got)
+	}
+	if !IsTradingTime(day("2022-09-30").Add(10*time.Hour)) || IsTradingTime(day("2022-09-30").Add(12*time.Hour)) {
+		t.Errorf("unexpect trading time")
+	}
+	if close := DayClose(day("2022-09-30")).Unix(); close != 1664521200 {
+		t.Errorf("unexpect day close: %d", close)
+	}
+
+	// 补充新年份的节假日数据
+	c := NewCalendar()
+	if err := c.AddHolidayData([]byte(`{"holidays": {"2023": ["01-02", "01-23"]}}`)); err != nil {
+		t.Fatalf("add holidays fail: err=%v", err)
+	}
+	if !c.IsCovered(2023) || c.IsTradingDay(day("2023-01-23")) || !c.IsTradingDay(day("2023-01-03")) {
+		t.Errorf("unexpect extended holidays")
+	}
+}
@@ -2,177 +2,370 @@ package common
 
 import (
 	"fmt"
-	"sort"
+	"github.com/BlackCarDriver/StockMaster/calendar"
+	"math"
 )
 
 // Account 交易账号
 type Account struct {
-	Name        string       `json:"name"`
-	Note        string       `json:"note"`                  // 备注
-	InitFundRMB float64      `json:"InitFundRMB"`           // 初始总资产
-	TargetStock string       `json:"targetSock"`            // 目标股票代码
-	Balance     BalanceInfo  `json:"BalanceInfo"`           // 账户余额信息
-	TradStat    TradInfo     `json:"TradInfo"`              // 交易过程统计数据
-	TradLog     []TradRecord `json:"tradLogList,omitempty"` // 交易记录
-	ActionLog   []Action     `json:"actionLog"`             // 操作日志
-	Setting     Setting      `json:"-"`                     // 过程变量
-	BuyEntrust  []Entrust    `json:"-"`                     // 买入委托单
-	SellEntrust []Entrust    `json:"-"`                     // 卖出委托单
-	LastPrize   *KLineNode   `json:"-"`                     // 最新股票状况
-	LastDeal    *KLineNode   `json:"-"`                     // 上次交易时的股票状况
-}
-
-// Trad 交易
-func (a *Account) Trad(mode OpMode, prize float64, vol int, moment KLineNode) (isOk bool, reason string) {
-	if vol <= 0 || prize == 0 || mode == ModeWait {
-		log.Warning("不合法的输入: prize=%f vol=%d moment=%+v mode=%v ", prize, vol, moment, mode)
-		reason = "参数错误"
+	Name        string          `json:"name"`
+	Note        string          `json:"note"`                  // 备注
+	InitFundRMB float64         `json:"InitFundRMB"`           // 初始总资产
+	TargetStock string          `json:"targetStock"`           // 目标股票代码
+	TargetName  string          `json:"targetName,omitempty"`  // 目标股票名称, 用于判断ST股票的涨跌幅限制
+	T0          bool            `json:"t0"`                    // 是否允许当日买入当日卖出 (跨境/债券/黄金等ETF), 默认T+1
+	Rule        *InstrumentRule `json:"rule,omitempty"`        // 委托规则 (nil=根据TargetStock获取默认规则)
+	Balance     BalanceInfo     `json:"BalanceInfo"`           // 账户余额信息
+	TradStat    TradInfo        `json:"TradInfo"`              // 交易过程统计数据
+	TradLog     []TradRecord    `json:"tradLogList,omitempty"` // 交易记录
+	ActionLog   []Action        `json:"actionLog"`             // 操作日志
+	Lots        []PositionLot   `json:"lots,omitempty"`        // 持仓批次 (初始化时已持有的份额在首次交易时记录为一个批次)
+	CostMethod  CostMethod      `json:"costMethod"`            // 卖出时计算持仓成本的方式 (空=先进先出)
+	Limit       PriceLimit      `json:"limit"`                 // 当日涨跌停价格, 由UpdateStat在每个交易日开始时更新
+	Margin      *MarginConfig   `json:"margin,omitempty"`      // 融资融券配置 (nil=普通现金账户)
+	Debt        MarginDebt      `json:"debt"`                  // 融资融券负债
+	YieldDay    string          `json:"yieldDay,omitempty"`    // 上次计算闲置现金收益的交易日
+	Sampling    EquitySampling  `json:"sampling"`              // 资产曲线的采样频率 (默认不记录)
+	Equity      []EquityPoint   `json:"equity,omitempty"`      // 资产曲线, 模拟时在每个节点执行策略之后按采样频率记录
+	Setting     Setting         `json:"-"`                     // 过程变量
+	BuyEntrust  []Entrust       `json:"-"`                     // 买入委托单
+	SellEntrust []Entrust       `json:"-"`                     // 卖出委托单
+	Closed      []Entrust       `json:"-"`                     // 已成交, 撤销或失效的委托单 (执行委托时从委托列表移出)
+	Rejected    []Entrust       `json:"-"`                     // 检查不通过被拒绝的委托单
+	EntrustSeq  int             `json:"-"`                     // 已分配的最大委托编号
+	LotSeq      int             `json:"-"`                     // 已分配的最大持仓批次编号
+	LastPrize   *KLineNode      `json:"-"`                     // 最新股票状况
+	LastDeal    *KLineNode      `json:"-"`                     // 上次交易时的股票状况
+	FeeModel    FeeModel        `json:"-"`                     // 交易费用计算方式 (nil=不收取费用)
+	Execution   ExecutionModel  `json:"-"`                     // 成交模型 (nil=按委托价全部成交)
+	PricePath   PricePath       `json:"-"`                     // 节点内价格路径模型 (nil=按OHLCPath推测)
+	CashYield   CashYield       `json:"-"`                     // 闲置现金收益率 (nil=现金没有收益)
+
+	portfolio *Portfolio // 所属的组合账号, 不为空时使用组合共享的现金
+}
+
+// Trad 交易, 按照账号的成交模型成交, 成交模型限制成交量时可能只成交部分份额
+// 交易失败时返回*TradError, 可以通过errors.Is判断失败类型, 例如 errors.Is(err, ErrInsufficientCash)
+func (a *Account) Trad(mode OpMode, prize float64, vol int, moment KLineNode) (err error) {
+	_, err = a.deal(mode, prize, vol, moment)
+	return
+}
+
+// 按照委托价格和数量交易, 返回实际成交份额, 交易失败时记录操作日志
+func (a *Account) deal(mode OpMode, prize float64, vol int, moment KLineNode) (dealVol int, err error) {
+	return a.dealLot(mode, prize, vol, 0, moment)
+}
+
+// 按照委托价格和数量交易, 卖出时lotID大于0表示优先卖出指定的持仓批次
+func (a *Account) dealLot(mode OpMode, prize float64, vol int, lotID int, moment KLineNode) (dealVol int, err error) {
+	entrustPrize, entrustVol := prize, vol
+	if prize, vol, err = a.checkDeal(mode, prize, vol, a.Balance.StockVol, moment); err != nil {
 		return
 	}
-
-	// 放弃交易的情况
 	value := prize * float64(vol) // 交易金额
-	if mode == ModeBuy && value > a.Balance.BalanceRMB {
-		reason = fmt.Sprintf("余额不足,无法买入: 均价=%.2f 委托价=%.2f 请求扣费=%.2f 余额=%.2f ",
-			moment.GetAveragePrice(), prize, value, a.Balance.BalanceRMB)
-		a.recordAction(moment.Timestamp, ActionGiveUp, reason)
+	fee := a.CountFee(mode, prize, vol)
+	if mode == ModeBuy && value+fee > a.Cash() && !a.canFinance(value+fee-a.Cash(), prize) {
+		err = newTradError(ErrInsufficientCash, "余额不足,无法买入: 均价=%.2f 委托价=%.2f 请求扣费=%.2f 余额=%.2f ",
+			moment.GetAveragePrice(), prize, value+fee, a.Cash())
+		a.recordAction(moment.Timestamp, ActionGiveUp, err.Error())
 		return
 	}
 	if mode == ModeShell && a.Balance.StockVol-vol < 0 {
-		reason = fmt.Sprintf("份额不足,无法卖出: 均价=%.2f 委托价=%.2f 请求卖出=%d 持有份额=%d",
+		err = newTradError(ErrInsufficientShares, "份额不足,无法卖出: 均价=%.2f 委托价=%.2f 请求卖出=%d 持有份额=%d",
 			moment.GetAveragePrice(), prize, vol, a.Balance.StockVol)
-		a.recordAction(moment.Timestamp, ActionGiveUp, reason)
+		a.recordAction(moment.Timestamp, ActionGiveUp, err.Error())
 		return
 	}
-	if mode == ModeShell && a.Setting.SellLock {
-		reason = fmt.Sprintf("卖出操作被禁止,无法卖出: 均价=%.2f 委托价=%.2f", moment.GetAveragePrice(), prize)
-	}
-	if mode == ModeBuy && a.Setting.BuyLock {
-		reason = fmt.Sprintf("买入操作被禁止,无法买入: 均价=%.2f 委托价=%.2f", moment.GetAveragePrice(), prize)
+	if sellable := a.SellableVol(moment.Timestamp); mode == ModeShell && vol > sellable {
+		err = newTradError(ErrSettlement, "T+1限制,可卖份额不足: 委托价=%.2f 请求卖出=%d 可卖份额=%d 持有份额=%d",
+			prize, vol, sellable, a.Balance.StockVol)
+		a.recordAction(moment.Timestamp, ActionGiveUp, err.Error())
+		return
 	}
 
 	// 买入或卖出指定数量,更新账号信息
+	a.commitFill(moment, vol)
+	a.syncLots()
 	if mode == ModeBuy {
-		a.Balance.BalanceRMB -= value
-		a.Balance.CostRMB += value
+		a.addCash(-(value + fee))
+		a.Balance.CostRMB += value + fee
 		a.Balance.StockVol += vol
-		a.recordAction(moment.Timestamp, ActionBuy, fmt.Sprintf("成功买入, 价格区间=[%.2f~%.2f] 成交份额=%d 成交价=%.2f 成交金额=%.2f",
-			moment.Bottom, moment.Top, vol, prize, value))
-		a.recordTradLog(moment.Timestamp, ModeBuy, prize, vol)
+		a.addLot(moment.Timestamp, vol, value+fee)
+		a.TradStat.TotalFee += fee
+		a.recordAction(moment.Timestamp, ActionBuy, fmt.Sprintf("成功买入, 价格区间=[%.2f~%.2f] 成交份额=%d 成交价=%.2f 成交金额=%.2f 手续费=%.2f",
+			moment.Bottom, moment.Top, vol, prize, value, fee))
+		a.recordTradLog(moment.Timestamp, ModeBuy, prize, vol, fee, entrustPrize, entrustVol, 0)
+		a.financeShortage(moment.Timestamp)
 		a.LastDeal = &moment
 		a.maintainTradStat(ModeBuy)
 	}
 	if mode == ModeShell {
+		cost := a.takeLots(vol, lotID, moment.Timestamp) // 卖出份额的持仓成本
+		pnl := value - fee - cost
 		a.Balance.StockVol -= vol
-		a.Balance.BalanceRMB += value
-		a.Balance.CostRMB -= value
-		a.recordAction(moment.Timestamp, ActionShell, fmt.Sprintf("成功卖出, 价格区间=[%.2f~%.2f] 成交份额=%d 成交价=%.2f 成交金额=%.2f",
-			moment.Bottom, moment.Top, vol, prize, value))
-		a.recordTradLog(moment.Timestamp, ModeShell, prize, vol)
+		a.addCash(value - fee)
+		a.Balance.CostRMB -= cost
+		if a.Balance.StockVol == 0 {
+			a.Balance.CostRMB = 0 // 清仓时消除累计的计算误差
+		}
+		a.TradStat.TotalFee += fee
+		a.TradStat.RealizedPnL += pnl
+		a.recordAction(moment.Timestamp, ActionShell, fmt.Sprintf("成功卖出, 价格区间=[%.2f~%.2f] 成交份额=%d 成交价=%.2f 成交金额=%.2f 手续费=%.2f 持仓成本=%.2f 实现盈亏=%.2f",
+			moment.Bottom, moment.Top, vol, prize, value, fee, cost, pnl))
+		a.recordTradLog(moment.Timestamp, ModeShell, prize, vol, fee, entrustPrize, entrustVol, pnl)
+		a.repayFinance(moment.Timestamp)
 		a.LastDeal = &moment
 		a.maintainTradStat(ModeShell)
 	}
 
 	// 维护其他交易信息
-	return true, ""
+	return vol, nil
 }
 
-// CreateEntrust 创建条件单
-func (a *Account) CreateEntrust(mode OpMode, prize float64, vol int, startTime int64, deadTime int64) {
-	item := Entrust{
-		StarTime: startTime,
-		DeadTime: deadTime,
-		Price:    prize,
-		Vol:      vol,
-		DealTime: 0,
+// 检查委托是否符合交易规则并按成交模型计算成交价格和份额, 检查不通过时记录操作日志
+// holdVol为卖出时可以一次性卖出零股的持有份额
+func (a *Account) checkDeal(mode OpMode, prize float64, vol int, holdVol int, moment KLineNode) (dealPrize float64, dealVol int, err error) {
+	if vol <= 0 || prize <= 0 || (mode != ModeBuy && mode != ModeShell) {
+		log.Warning("不合法的输入: prize=%f vol=%d moment=%+v mode=%v ", prize, vol, moment, mode)
+		err = newTradError(ErrInvalidParams, "参数错误: prize=%f vol=%d mode=%v", prize, vol, mode)
+		a.recordAction(moment.Timestamp, ActionGiveUp, err.Error())
+		return
 	}
-	expireDesc := ""
-	if deadTime > 0 {
-		expireDesc = fmt.Sprintf(" (%s截至)", TimeFormat(deadTime))
+	if err = a.checkLock(mode, prize, moment); err != nil {
+		return
 	}
-	if mode == ModeBuy {
-		a.BuyEntrust = append(a.BuyEntrust, item)
-		a.recordAction(startTime, ActionEntrust, fmt.Sprintf("创建条件单, 价格下破 %.2f 时买入 %d 份 %s", prize, vol, expireDesc))
-		sort.Slice(a.BuyEntrust, func(i, j int) bool {
-			return a.BuyEntrust[i].Price > a.BuyEntrust[j].Price
-		})
+	rule := a.GetRule()
+	if isLegal, ruleReason := rule.CheckVol(mode, vol, holdVol); !isLegal {
+		err = newTradError(ErrLotSize, "%s", ruleReason)
+		a.recordAction(moment.Timestamp, ActionGiveUp, err.Error())
+		return
 	}
-	if mode == ModeShell {
-		a.SellEntrust = append(a.SellEntrust, item)
-		a.recordAction(startTime, ActionEntrust, fmt.Sprintf("创建条件单, 价格上穿 %.2f 时卖出 %d 份 %s", prize, vol, expireDesc))
-		sort.Slice(a.SellEntrust, func(i, j int) bool {
-			return a.SellEntrust[i].Price < a.SellEntrust[j].Price
-		})
+	if isLegal, ruleReason := rule.CheckPrice(prize); !isLegal {
+		err = newTradError(ErrPriceTick, "%s", ruleReason)
+		a.recordAction(moment.Timestamp, ActionGiveUp, err.Error())
+		return
 	}
-}
-
-// ExecuteEntrust 检查是否有可触发的条件单,自动执行并返回对应的委托信息(卖出优先)
-func (a *Account) ExecuteEntrust(moment KLineNode) (mode OpMode, record *Entrust) {
-	// 尝试执行达到条件的卖出委托
-	for i, entrust := range a.SellEntrust {
-		if entrust.DeadTime > 0 && entrust.DeadTime < moment.Timestamp { // 已过期
-			a.SellEntrust[i].DealTime = entrust.DeadTime
-			continue
-		}
-		if a.Setting.SellLock {
-			return
-		}
-		if entrust.DealTime > 0 {
-			continue
-		}
-		if moment.Top < entrust.Price { // 价格未上穿卖出价
-			continue
-		}
-		isDeal, _ := a.Trad(ModeShell, entrust.Price, entrust.Vol, moment)
-		if !isDeal {
-			break
-		}
-		a.SellEntrust[i].DealTime = moment.Timestamp // 执行成功
-		mode = ModeShell
-		record = &a.SellEntrust[i]
+	if !a.Limit.Contains(prize) {
+		err = newTradError(ErrPriceLimit, "价格超出涨跌停范围,无法%s: 委托价=%.3f 涨停价=%.3f 跌停价=%.3f", mode, prize, a.Limit.Up, a.Limit.Down)
+		a.recordAction(moment.Timestamp, ActionLimit, err.Error())
 		return
 	}
-	// 尝试执行达到条件的买入委托
-	for i, entrust := range a.BuyEntrust {
-		if entrust.DeadTime > 0 && entrust.DeadTime < moment.Timestamp { // 已过期
-			a.BuyEntrust[i].DealTime = entrust.DeadTime
-			continue
-		}
-		if a.Setting.BuyLock {
-			return
-		}
-		if entrust.DealTime > 0 {
-			continue
-		}
-		if moment.Bottom > entrust.Price { // 未跌破到指定买入价
-			continue
-		}
-		isDeal, _ := a.Trad(ModeBuy, entrust.Price, entrust.Vol, moment)
-		if !isDeal {
-			break
-		}
-		a.BuyEntrust[i].DealTime = moment.Timestamp
-		mode = ModeBuy
-		record = &a.BuyEntrust[i]
+	if a.Limit.IsLocked(moment) {
+		err = newTradError(ErrPriceLimit, "一字涨跌停,无法%s: 价格=%.3f 涨停价=%.3f 跌停价=%.3f", mode, moment.End, a.Limit.Up, a.Limit.Down)
+		a.recordAction(moment.Timestamp, ActionLimit, err.Error())
+		return
+	}
+
+	// 放弃交易的情况
+	if dealPrize, dealVol = a.fill(mode, prize, vol, moment); dealVol <= 0 {
+		err = newTradError(ErrNoLiquidity, "成交量不足,无法%s: 委托价=%.3f 委托份额=%d 节点成交量=%.0f", mode, prize, vol, moment.Vol)
+		a.recordAction(moment.Timestamp, ActionGiveUp, err.Error())
 		return
 	}
-	mode = ModeWait
 	return
 }
 
+// 检查买入或卖出操作是否被禁止, 被禁止时记录操作日志
+func (a *Account) checkLock(mode OpMode, prize float64, moment KLineNode) (err error) {
+	if !a.isLocked(mode) {
+		return nil
+	}
+	err = newTradError(ErrLocked, "%s操作被禁止,无法%s: 均价=%.2f 委托价=%.2f", mode, mode, moment.GetAveragePrice(), prize)
+	a.recordAction(moment.Timestamp, ActionGiveUp, err.Error())
+	return
+}
+
+func (a *Account) isLocked(mode OpMode) bool {
+	return (mode == ModeShell && a.Setting.SellLock) || (mode == ModeBuy && a.Setting.BuyLock)
+}
+
+// 成交完成后通知需要记录成交的成交模型
+func (a *Account) commitFill(moment KLineNode, vol int) {
+	if committer, ok := a.Execution.(FillCommitter); ok {
+		committer.Commit(moment, vol)
+	}
+}
+
+// 使用成交模型计算实际成交价格和份额, 成交价调整为合法价格, 部分成交时份额向下取整到整手
+func (a *Account) fill(mode OpMode, prize float64, vol int, moment KLineNode) (dealPrize float64, dealVol int) {
+	if a.Execution == nil {
+		return prize, vol
+	}
+	rule := a.GetRule()
+	dealPrize, dealVol = a.Execution.Fill(mode, prize, vol, moment)
+	dealPrize = rule.SnapPrice(dealPrize)
+	if dealVol >= vol {
+		return dealPrize, vol
+	}
+	if rule.LotSize > 1 {
+		dealVol = dealVol / rule.LotSize * rule.LotSize
+	}
+	return dealPrize, dealVol
+}
+
 // UpdateStat 更新统计信息维护状态变量
+// 进入新的交易日时计算闲置现金收益和融资融券利息, 融资融券账户检查维持担保比例
 func (a *Account) UpdateStat(moment KLineNode) {
+	a.accrueCashYield(moment)
+	a.accrueInterest(moment)
+	a.updateLimit(moment)
 	a.LastPrize = &moment
 	a.maintainTradStat(ModeWait)
+	a.checkMaintenance(moment)
+}
+
+// UpdateAdjust 除权除息日按照复权因子的变化补偿持仓损失的市值, 使收益按复权价格计算
+// 需要在UpdateStat更新最新价格之前调用, prevFactor和factor分别为上一个节点和当前节点的复权因子
+func (a *Account) UpdateAdjust(prevFactor, factor float64) {
+	if a.LastPrize == nil || prevFactor <= 0 || factor <= 0 || prevFactor == factor {
+		return
+	}
+	a.TradStat.AdjustGain += float64(a.Balance.StockVol) * a.LastPrize.End * (1 - prevFactor/factor)
+}
+
+// ApplyExRight 在除权除息日处理持仓的分红送转, 并按除权参考价调整未成交委托单的价格
+// cash为每股派息, bonus为每股送转股数 (拆股合股同样按比例表示)
+func (a *Account) ApplyExRight(timestamp int64, cash float64, bonus float64) {
+	if cash < 0 || bonus <= -1 || (cash == 0 && bonus == 0) {
+		log.Warning("不合法的除权除息参数: cash=%f bonus=%f", cash, bonus)
+		return
+	}
+	a.syncLots()
+	vol := a.Balance.StockVol
+	dividend := cash * float64(vol)
+	a.addCash(dividend)
+	a.Balance.CostRMB -= dividend // 分红摊薄持仓成本
+	a.spreadCost(-dividend)
+	bonusVol := a.scaleLots(bonus) // 送转后各批次的成本和买入时间不变, 总成本不变
+	a.Balance.StockVol += bonusVol
+	a.TradStat.DividendRMB += dividend
+	a.TradStat.BonusVol += bonusVol
+	if a.Debt.ShortVol > 0 { // 融券需要向出借人补偿分红和送转份额
+		a.addCash(-cash * float64(a.Debt.ShortVol))
+		a.Debt.ShortVol += int(math.Floor(float64(a.Debt.ShortVol) * bonus))
+		a.financeShortage(timestamp)
+	}
+
+	rule := a.GetRule() // 除权后的委托价格按价格精度调整, 否则成交时会因价格不合法被拒绝
+	adjustPrice := func(list []Entrust) {
+		for i := range list {
+			if list[i].IsOpen() {
+				list[i].Price = rule.SnapPrice((list[i].Price - cash) / (1 + bonus))
+			}
+			if list[i].IsOpen() && list[i].StopPrice > 0 {
+				list[i].StopPrice = rule.SnapPrice((list[i].StopPrice - cash) / (1 + bonus))
+			}
+			if list[i].IsOpen() && list[i].Extreme > 0 {
+				list[i].Extreme = rule.SnapPrice((list[i].Extreme - cash) / (1 + bonus))
+			}
+		}
+	}
+	adjustPrice(a.BuyEntrust)
+	adjustPrice(a.SellEntrust)
+	if a.LastPrize != nil { // 涨跌停价格按除权参考价计算
+		a.setLimit(tradeDay(timestamp), (a.LastPrize.End-cash)/(1+bonus))
+	}
+	a.recordAction(timestamp, ActionExRight, fmt.Sprintf("每股派息=%.4f 每股送转=%.4f 持有份额=%d 现金分红=%.2f 送转份额=%d",
+		cash, bonus, vol, dividend, bonusVol))
+}
+
+// CountFee 按照账号的费用模型计算交易费用
+func (a *Account) CountFee(mode OpMode, prize float64, vol int) float64 {
+	if a.FeeModel == nil {
+		return 0
+	}
+	return a.FeeModel.CountFee(a.TargetStock, mode, prize, vol)
+}
+
+// 新的交易日开始时按昨收价更新涨跌停价格, 日线数据的第一个节点使用交易所公布的昨收价 (收盘价-涨跌额)
+func (a *Account) updateLimit(moment KLineNode) {
+	today := tradeDay(moment.Timestamp)
+	if a.Limit.TradeDay == today {
+		return
+	}
+	if a.LastPrize != nil {
+		a.setLimit(today, a.LastPrize.End)
+		return
+	}
+	if len(moment.TimeDesc) <= len("2006-01-02") {
+		a.setLimit(today, moment.End-moment.PriceRise)
+	}
+}
+
+func (a *Account) setLimit(day string, prevClose float64) {
+	up, down := a.GetRule().LimitPrice(prevClose)
+	a.Limit = PriceLimit{TradeDay: day, PrevClose: prevClose, Up: up, Down: down}
+}
+
+// GetRule 获取账号交易品种的委托规则
+func (a *Account) GetRule() InstrumentRule {
+	if a.Rule != nil {
+		return *a.Rule
+	}
+	return GetInstrumentRule(a.TargetStock, a.TargetName)
+}
+
+// LegalVol 获取最接近vol的合法委托数量 (卖出时不超过持有份额)
+func (a *Account) LegalVol(mode OpMode, vol int) int {
+	return a.GetRule().LegalVol(mode, vol, a.Balance.StockVol)
+}
+
+// LegalPrice 获取最接近prize的合法委托价格
+func (a *Account) LegalPrice(prize float64) float64 {
+	return a.GetRule().SnapPrice(prize)
+}
+
+// SellableVol 获取指定时间的可卖份额, T+1制度下当日买入的份额不能卖出
+func (a *Account) SellableVol(timestamp int64) int {
+	if a.T0 {
+		return a.Balance.StockVol
+	}
+	today := tradeDay(timestamp)
+	sellable := a.Balance.StockVol
+	for _, lot := range a.Lots {
+		if lot.TradeDay == today {
+			sellable -= lot.Vol
+		}
+	}
+	if sellable < 0 {
+		return 0
+	}
+	return sellable
+}
+
+// Cash 获取可用现金, 属于组合账号时为组合共享的现金
+func (a *Account) Cash() float64 {
+	if a.portfolio != nil {
+		return a.portfolio.BalanceRMB
+	}
+	return a.Balance.BalanceRMB
+}
+
+// 增加或减少可用现金
+func (a *Account) addCash(amount float64) {
+	if a.portfolio != nil {
+		a.portfolio.BalanceRMB += amount
+		return
+	}
+	a.Balance.BalanceRMB += amount
+}
+
+// 获取时间戳所在的交易日
+func tradeDay(timestamp int64) string {
+	return calendar.InLocation(timestamp).Format("2006-01-02")
 }
 
 // 保存交易记录
-func (a *Account) recordTradLog(timestamp int64, mode OpMode, prize float64, vol int) {
+func (a *Account) recordTradLog(timestamp int64, mode OpMode, prize float64, vol int, fee float64, entrustPrize float64, entrustVol int, pnl float64) {
 	record := TradRecord{
-		Timestamp: timestamp,
-		Mode:      mode,
-		Prize:     prize,
-		Vol:       vol,
+		Timestamp:    timestamp,
+		Mode:         mode,
+		Prize:        prize,
+		Vol:          vol,
+		Fee:          fee,
+		EntrustPrize: entrustPrize,
+		EntrustVol:   entrustVol,
+		RealizedPnL:  pnl,
 	}
 	a.TradLog = append(a.TradLog, record)
 }
@@ -216,7 +409,11 @@ func (a *Account) maintainTradStat(mode OpMode) {
 		a.TradStat.MinCost = a.Balance.CostRMB
 	}
 
-	total := a.LastPrize.End*float64(a.Balance.StockVol) + a.Balance.BalanceRMB // 当前账号总资产
+	if a.portfolio != nil { // 子账号没有独立的现金, 总资产只在组合中统计
+		a.portfolio.maintainTradStat()
+		return
+	}
+	total := a.NetAsset() // 当前账号总资产
 
 	if total > a.TradStat.MaxValue {
 		a.TradStat.MaxValue = total
@@ -0,0 +1,49 @@
+package common
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSettlementT1(t *testing.T) {
+	var nodes []KLineNode
+	for _, desc := range []string{"2022-06-01 10:00", "2022-06-01 14:00", "2022-06-02 10:00"} {
+		nodes = append(nodes, mockNode(desc, 10))
+	}
+	account := mockAccount("T1", 100000)
+	account.Balance.StockVol = 100
+	account.UpdateStat(nodes[0])
+	if err := account.Trad(ModeBuy, 10, 1000, nodes[0]); err != nil {
+		t.Fatalf("buy fail: err=%v", err)
+	}
+	if got := account.SellableVol(nodes[1].Timestamp); got != 100 {
+		t.Fatalf("expect only initial position sellable on buy day: got=%d", got)
+	}
+	if err := account.Trad(ModeShell, 10, 500, nodes[1]); !errors.Is(err, ErrSettlement) {
+		t.Fatalf("expect same day sell rejected: err=%v", err)
+	}
+
+	// 委托单在当日不能成交, 下一交易日成交
+	account.CreateEntrust(ModeShell, 10.2, 500, nodes[1].Timestamp, 0)
+	if mode, _ := account.ExecuteEntrust(nodes[1]); mode != ModeWait {
+		t.Fatalf("expect sell entrust deferred: mode=%s", mode)
+	}
+	if n := len(account.ActionLog); account.ActionLog[n-1].Mode != ActionGiveUp || !strings.Contains(account.ActionLog[n-1].Desc, "T+1限制") {
+		t.Fatalf("expect deferred sell logged: %+v", account.ActionLog[n-1])
+	}
+	if mode, _ := account.ExecuteEntrust(nodes[2]); mode != ModeShell {
+		t.Fatalf("expect sell entrust executed next day: mode=%s", mode)
+	}
+	if account.Balance.StockVol != 600 || len(account.Lots) != 1 || account.Lots[0].Vol != 600 {
+		t.Fatalf("unexpect position: vol=%d lots=%+v", account.Balance.StockVol, account.Lots)
+	}
+
+	account = mockAccount("T0", 100000)
+	account.TargetStock = "1.513050"
+	account.T0 = IsT0Code(account.TargetStock)
+	account.Trad(ModeBuy, 10, 1000, nodes[0])
+	if err := account.Trad(ModeShell, 10, 1000, nodes[1]); err != nil {
+		t.Fatalf("expect T+0 sell ok: err=%v", err)
+	}
+}
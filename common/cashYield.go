@@ -0,0 +1,107 @@
+package common
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// CashYield 闲置现金的年化收益率, 例如国债逆回购或货币基金的收益率, 收益按365天计算
+type CashYield interface {
+	AnnualRate(day string) float64 // 获取指定交易日的年化收益率, day格式: 2006-01-02
+}
+
+// FixedYield 固定的年化收益率
+type FixedYield struct {
+	Rate float64 `json:"rate"` // 年化收益率, 例如2%为0.02
+}
+
+// AnnualRate 获取指定交易日的年化收益率
+func (f *FixedYield) AnnualRate(day string) float64 {
+	return f.Rate
+}
+
+// YieldPoint 收益率序列中的一个数据点
+type YieldPoint struct {
+	Day  string  `json:"day"`  // 生效日期, 格式: 2006-01-02
+	Rate float64 `json:"rate"` // 年化收益率, 例如2%为0.02
+}
+
+// YieldSeries 随时间变化的年化收益率, 每个数据点的收益率一直有效到下一个数据点, 第一个数据点之前为0
+type YieldSeries struct {
+	Points []YieldPoint `json:"points"` // 按日期升序排列
+}
+
+// NewYieldSeries 使用数据点创建收益率序列, 数据点按日期排序
+func NewYieldSeries(points []YieldPoint) *YieldSeries {
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].Day < points[j].Day
+	})
+	return &YieldSeries{Points: points}
+}
+
+// AnnualRate 获取指定交易日的年化收益率
+func (y *YieldSeries) AnnualRate(day string) float64 {
+	idx := sort.Search(len(y.Points), func(i int) bool {
+		return y.Points[i].Day > day
+	})
+	if idx == 0 {
+		return 0
+	}
+	return y.Points[idx-1].Rate
+}
+
+// 进入新的交易日时按自然日计算闲置现金的收益, 收益计入现金, 使用上一个计息日的收益率
+// 属于组合账号的子账号不单独计算, 由组合按共享现金计算
+func (a *Account) accrueCashYield(moment KLineNode) {
+	today := tradeDay(moment.Timestamp)
+	if a.CashYield == nil || a.portfolio != nil || today <= a.YieldDay {
+		return
+	}
+	interest, days, rate := countCashYield(a.CashYield, a.Cash(), a.YieldDay, today)
+	a.YieldDay = today
+	if interest <= 0 {
+		return
+	}
+	cash := a.Cash()
+	a.addCash(interest)
+	a.TradStat.CashInterest += interest
+	a.recordAction(moment.Timestamp, ActionCashYield, fmt.Sprintf("计息天数=%.0f 年化收益率=%.3f%% 闲置现金=%.2f 收益=%.2f", days, rate*100, cash, interest))
+}
+
+// 进入新的交易日时按组合的共享现金计算闲置现金的收益
+func (p *Portfolio) accrueCashYield(moment KLineNode) {
+	today := tradeDay(moment.Timestamp)
+	if p.CashYield == nil || today <= p.YieldDay {
+		return
+	}
+	interest, days, rate := countCashYield(p.CashYield, p.BalanceRMB, p.YieldDay, today)
+	p.YieldDay = today
+	if interest <= 0 {
+		return
+	}
+	cash := p.BalanceRMB
+	p.BalanceRMB += interest
+	p.TradStat.CashInterest += interest
+	p.recordAction(moment.Timestamp, ActionCashYield, fmt.Sprintf("计息天数=%.0f 年化收益率=%.3f%% 闲置现金=%.2f 收益=%.2f", days, rate*100, cash, interest))
+}
+
+// 计算从上一个计息日到当前交易日之间的现金收益
+func countCashYield(yield CashYield, cash float64, lastDay string, today string) (interest float64, days float64, rate float64) {
+	if lastDay == "" || cash <= 0 {
+		return
+	}
+	days, rate = naturalDays(lastDay, today), yield.AnnualRate(lastDay)
+	if days <= 0 || rate <= 0 {
+		return 0, days, rate
+	}
+	return cash * rate * days / 365, days, rate
+}
+
+// 计算两个交易日之间的自然日天数, 日期格式: 2006-01-02
+func naturalDays(from string, to string) float64 {
+	fromDay, _ := time.Parse("2006-01-02", from)
+	toDay, _ := time.Parse("2006-01-02", to)
+	return math.Round(toDay.Sub(fromDay).Hours() / 24)
+}
@@ -0,0 +1,37 @@
+package common
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCashYield(t *testing.T) {
+	nodes := []KLineNode{mockNode("2022-06-01", 10), mockNode("2022-06-02", 10), mockNode("2022-06-06", 10)}
+
+	// 固定收益率按自然日计息, 收益计入现金
+	account := mockAccount("Yield", 100000)
+	account.CashYield = &FixedYield{Rate: 0.0365}
+	for _, node := range nodes {
+		account.UpdateStat(node)
+	}
+	if math.Abs(account.TradStat.CashInterest-50.004) > 1e-6 || math.Abs(account.Balance.BalanceRMB-100050.004) > 1e-6 {
+		t.Fatalf("unexpect fixed yield: stat=%+v balance=%+v", account.TradStat, account.Balance)
+	}
+
+	// 组合按共享现金计息, 子账号不重复计算
+	portfolio := NewPortfolio("Yield", 100000)
+	portfolio.CashYield = &FixedYield{Rate: 0.0365}
+	for _, code := range []string{"510500", "513050"} {
+		portfolio.AddAccount(&Account{TargetStock: code, CashYield: &FixedYield{Rate: 0.0365}})
+	}
+	for _, node := range nodes[:2] {
+		portfolio.UpdateStat("510500", node)
+		portfolio.UpdateStat("513050", node)
+	}
+	if math.Abs(portfolio.TradStat.CashInterest-10) > 1e-6 || math.Abs(portfolio.BalanceRMB-100010) > 1e-6 {
+		t.Fatalf("unexpect portfolio yield: cash=%.2f stat=%+v", portfolio.BalanceRMB, portfolio.TradStat)
+	}
+	if n := len(portfolio.ActionLog); n != 1 || portfolio.ActionLog[0].Mode != ActionCashYield || portfolio.ActionLog[0].Timestamp != nodes[1].Timestamp {
+		t.Fatalf("expect portfolio yield logged: %+v", portfolio.ActionLog)
+	}
+}
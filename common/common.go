@@ -2,6 +2,7 @@ package common
 
 import (
 	"fmt"
+	"github.com/BlackCarDriver/StockMaster/calendar"
 	"github.com/astaxie/beego/logs"
 	"time"
 )
@@ -19,6 +20,27 @@ const (
 	ActionShell   ActionType = "成功卖出"
 	ActionEntrust ActionType = "创建委托"
 	ActionGiveUp  ActionType = "放弃交易"
+	ActionExRight ActionType = "除权除息"
+	ActionLimit   ActionType = "涨跌停限制"
+
+	ActionStopLoss  ActionType = "止损触发"
+	ActionStopBuy   ActionType = "突破买入触发"
+	ActionStopLimit ActionType = "止损限价触发"
+	ActionTrailing  ActionType = "跟踪止损触发"
+	ActionOCO       ActionType = "二选一撤单"
+	ActionBracket   ActionType = "括号单生效"
+	ActionFilled    ActionType = "委托成交"
+	ActionExpire    ActionType = "委托失效"
+	ActionCancel    ActionType = "撤销委托"
+	ActionAmend     ActionType = "修改委托"
+
+	ActionFinance    ActionType = "融资借款"
+	ActionRepay      ActionType = "融资还款"
+	ActionShortSell  ActionType = "融券卖出"
+	ActionCover      ActionType = "买券还券"
+	ActionInterest   ActionType = "融资融券计息"
+	ActionMarginCall ActionType = "强制平仓"
+	ActionCashYield  ActionType = "现金收益"
 )
 
 // KLineNode K线图节点
@@ -29,8 +51,8 @@ type KLineNode struct {
 	End       float64 `json:"spj"` // 收盘价
 	Top       float64 `json:"zgj"` // 最高
 	Bottom    float64 `json:"zdj"` // 最低
-	Vol       float64 `json:"cje"` // 成交额
-	Vov       float64 `json:"cjl"` // 成交量
+	Vol       float64 `json:"cje"` // 成交量 (手)
+	Vov       float64 `json:"cjl"` // 成交额 (元)
 	Wave      float64 `json:"zf"`  // 振幅
 	PriceWave float64 `json:"zdf"` // 涨跌幅
 	PriceRise float64 `json:"zde"` // 涨跌额
@@ -81,5 +103,5 @@ func RisePrizeByFlow(before float64, flow float64) (after float64) {
 
 // TimeFormat 格式化时间
 func TimeFormat(timestamp int64) string {
-	return time.Unix(timestamp, 0).Format("2006-01-02 15:04")
+	return calendar.InLocation(timestamp).Format("2006-01-02 15:04")
 }
@@ -0,0 +1,21 @@
+package common
+
+import (
+	"github.com/BlackCarDriver/StockMaster/calendar"
+	"time"
+)
+
+// 创建测试用的K线节点, desc为日期或分钟时间, 开盘价和收盘价为prize, 最高最低价在prize上下0.5元
+func mockNode(desc string, prize float64) KLineNode {
+	layout := "2006-01-02"
+	if len(desc) > len(layout) {
+		layout = "2006-01-02 15:04"
+	}
+	t, _ := time.ParseInLocation(layout, desc, calendar.Location)
+	return KLineNode{Timestamp: t.Unix(), TimeDesc: desc, Start: prize, End: prize, Top: prize + 0.5, Bottom: prize - 0.5}
+}
+
+// 创建测试用的账号, 交易品种为600036, 初始资金全部为现金
+func mockAccount(name string, fund float64) *Account {
+	return &Account{Name: name, InitFundRMB: fund, TargetStock: "600036", Balance: BalanceInfo{BalanceRMB: fund}}
+}
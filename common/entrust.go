@@ -0,0 +1,722 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+)
+
+// EntrustKind 委托单类型
+type EntrustKind string
+
+const (
+	KindLimit     EntrustKind = ""          // 限价条件单: 价格下破委托价时买入, 上穿委托价时卖出
+	KindStop      EntrustKind = "stop"      // 止损单: 价格跌破触发价时卖出 (止损), 突破触发价时买入 (追涨), 按触发时的价格成交
+	KindStopLimit EntrustKind = "stopLimit" // 止损限价单: 价格到达触发价后转为委托价的限价条件单
+	KindTrailing  EntrustKind = "trailing"  // 跟踪止损单: 卖出时触发价跟随最高价上移, 买入时跟随最低价下移
+)
+
+// EntrustStatus 委托单状态
+type EntrustStatus string
+
+const (
+	StatusPending   EntrustStatus = "pending"   // 等待成交
+	StatusPartial   EntrustStatus = "partial"   // 部分成交, 剩余份额继续等待成交
+	StatusFilled    EntrustStatus = "filled"    // 全部成交
+	StatusExpired   EntrustStatus = "expired"   // 到达失效时间
+	StatusCancelled EntrustStatus = "cancelled" // 已撤销 (主动撤销或二选一委托撤单)
+	StatusRejected  EntrustStatus = "rejected"  // 检查不通过, 未创建
+)
+
+// Desc 委托状态描述
+func (s EntrustStatus) Desc() string {
+	switch s {
+	case StatusPending:
+		return "等待成交"
+	case StatusPartial:
+		return "部分成交"
+	case StatusFilled:
+		return "全部成交"
+	case StatusExpired:
+		return "失效"
+	case StatusCancelled:
+		return "撤销"
+	case StatusRejected:
+		return "被拒绝"
+	}
+	return string(s)
+}
+
+// 单个节点内最多成交次数, 避免回调创建的委托单按当前价格反复成交
+const maxPathFills = 1000
+
+// FillCallback 委托单成交后的回调, record为成交后的委托单状态
+type FillCallback func(mode OpMode, record Entrust)
+
+// NewLimitEntrust 创建限价条件单: 买入时价格下破prize触发, 卖出时价格上穿prize触发
+func NewLimitEntrust(mode OpMode, prize float64, vol int, startTime int64, deadTime int64) Entrust {
+	return Entrust{Mode: mode, StarTime: startTime, DeadTime: deadTime, Price: prize, Vol: vol}
+}
+
+// NewStopEntrust 创建止损单: 卖出时价格跌破stopPrice触发, 买入时价格突破stopPrice触发
+func NewStopEntrust(mode OpMode, stopPrice float64, vol int, startTime int64, deadTime int64) Entrust {
+	return Entrust{Mode: mode, Kind: KindStop, StarTime: startTime, DeadTime: deadTime, Price: stopPrice, StopPrice: stopPrice, Vol: vol}
+}
+
+// NewStopLimitEntrust 创建止损限价单: 价格到达stopPrice后, 按limitPrice挂出限价条件单
+func NewStopLimitEntrust(mode OpMode, stopPrice float64, limitPrice float64, vol int, startTime int64, deadTime int64) Entrust {
+	return Entrust{Mode: mode, Kind: KindStopLimit, StarTime: startTime, DeadTime: deadTime, Price: limitPrice, StopPrice: stopPrice, Vol: vol}
+}
+
+// NewTrailingEntrust 创建跟踪止损单, refPrice为创建时的参考价, 回撤比例rate和回撤金额amount二选一 (rate优先)
+// 卖出时触发价为 最高价*(1-rate) 或 最高价-amount, 买入时为 最低价*(1+rate) 或 最低价+amount
+func NewTrailingEntrust(mode OpMode, refPrice float64, rate float64, amount float64, vol int, startTime int64, deadTime int64) Entrust {
+	item := Entrust{Mode: mode, Kind: KindTrailing, StarTime: startTime, DeadTime: deadTime, Vol: vol,
+		TrailRate: rate, TrailAmount: amount, Extreme: refPrice}
+	item.Price = item.TriggerPrice()
+	return item
+}
+
+// CreateEntrust 创建限价条件单, 委托价调整为最接近的合法价格, 委托数量不合法时不创建, 返回委托编号
+func (a *Account) CreateEntrust(mode OpMode, prize float64, vol int, startTime int64, deadTime int64) (id int, err error) {
+	return a.PlaceEntrust(NewLimitEntrust(mode, prize, vol, startTime, deadTime))
+}
+
+// PlaceEntrust 检查并添加委托单, 返回委托编号, 检查不通过的委托单记录到Rejected
+func (a *Account) PlaceEntrust(item Entrust) (id int, err error) {
+	item.ID = a.nextEntrustID()
+	if item, err = a.checkEntrust(item); err != nil {
+		a.rejectEntrust(item)
+		return item.ID, err
+	}
+	a.addEntrust(item)
+	return item.ID, nil
+}
+
+// PlaceOCO 添加二选一委托单, 其中一个成交后另一个自动撤销, 例如同时设置止盈和止损, 返回两个委托的编号
+func (a *Account) PlaceOCO(first Entrust, second Entrust) (ids []int, err error) {
+	first.ID, second.ID = a.nextEntrustID(), a.nextEntrustID()
+	if first, err = a.checkEntrust(first); err == nil {
+		second, err = a.checkEntrust(second)
+	}
+	if err != nil {
+		a.rejectEntrust(first, second)
+		return
+	}
+	first.Group = a.nextGroup()
+	second.Group = first.Group
+	a.addEntrust(first)
+	a.addEntrust(second)
+	return []int{first.ID, second.ID}, nil
+}
+
+// PlaceBracket 添加括号单: 建仓委托全部成交后, 止盈和止损委托开始生效并组成二选一委托
+// 止盈止损委托的份额默认与建仓委托相同, 建仓委托部分成交后被撤销或失效时按已成交份额生效, 返回建仓, 止盈, 止损委托的编号
+func (a *Account) PlaceBracket(entry Entrust, takeProfit Entrust, stopLoss Entrust) (ids []int, err error) {
+	for _, item := range []*Entrust{&takeProfit, &stopLoss} {
+		if item.Vol == 0 {
+			item.Vol = entry.Vol
+		}
+	}
+	entry.ID, takeProfit.ID, stopLoss.ID = a.nextEntrustID(), a.nextEntrustID(), a.nextEntrustID()
+	if entry, err = a.checkEntrust(entry); err == nil {
+		if takeProfit, err = a.checkEntrust(takeProfit); err == nil {
+			stopLoss, err = a.checkEntrust(stopLoss)
+		}
+	}
+	if err != nil {
+		a.rejectEntrust(entry, takeProfit, stopLoss)
+		return
+	}
+	entry.Group = a.nextGroup()
+	takeProfit.Group, stopLoss.Group = entry.Group+1, entry.Group+1
+	takeProfit.After, stopLoss.After = entry.Group, entry.Group
+	a.addEntrust(entry)
+	a.addEntrust(takeProfit)
+	a.addEntrust(stopLoss)
+	return []int{entry.ID, takeProfit.ID, stopLoss.ID}, nil
+}
+
+// CancelEntrust 撤销仍在等待成交的委托单, 部分成交的委托单撤销剩余份额, 未成交的建仓委托撤销时一并撤销其括号单
+func (a *Account) CancelEntrust(id int, timestamp int64) (err error) {
+	list, idx := a.findEntrust(id)
+	if idx < 0 {
+		return newTradError(ErrEntrustNotFound, "委托单不存在: id=%d", id)
+	}
+	if !list[idx].IsOpen() {
+		return newTradError(ErrEntrustClosed, "委托单已%s, 无法撤销: id=%d", list[idx].StatusDesc(), id)
+	}
+	a.closeEntrust(list, idx, StatusCancelled, timestamp, ActionCancel, "主动撤销")
+	return nil
+}
+
+// CancelAll 撤销指定方向上所有仍在等待成交的委托单 (包括未生效的括号单), 返回撤销数量
+func (a *Account) CancelAll(mode OpMode, timestamp int64) (count int) {
+	list := a.entrustList(mode)
+	for i := range list {
+		if list[i].IsOpen() {
+			a.closeEntrust(list, i, StatusCancelled, timestamp, ActionCancel, "全部撤销")
+			count++
+		}
+	}
+	return
+}
+
+// AmendEntrust 修改仍在等待成交的委托单, prize为新的委托价(止损单为触发价), vol为新的委托总份数 (不能少于已成交份数)
+// prize或vol不大于0时保持不变, 跟踪止损单的委托价随行情变化, 只能修改份数
+func (a *Account) AmendEntrust(id int, prize float64, vol int, timestamp int64) (err error) {
+	list, idx := a.findEntrust(id)
+	if idx < 0 {
+		return newTradError(ErrEntrustNotFound, "委托单不存在: id=%d", id)
+	}
+	origin := list[idx]
+	if !origin.IsOpen() {
+		return newTradError(ErrEntrustClosed, "委托单已%s, 无法修改: id=%d", origin.StatusDesc(), id)
+	}
+	item := origin
+	if prize > 0 {
+		switch item.Kind {
+		case KindTrailing:
+			return newTradError(ErrInvalidParams, "跟踪止损单不能修改委托价: id=%d", id)
+		case KindStop:
+			item.Price, item.StopPrice = prize, prize
+		default:
+			item.Price = prize
+		}
+	}
+	if vol > 0 {
+		item.Vol = vol
+	}
+	if item.RemainVol() <= 0 {
+		return newTradError(ErrInvalidParams, "委托份数不能少于已成交份数: vol=%d filledVol=%d", item.Vol, item.FilledVol)
+	}
+	item.StarTime = timestamp
+	check := item
+	check.Vol, check.FilledVol = item.RemainVol(), 0 // 只检查剩余份额
+	if check, err = a.checkEntrust(check); err != nil {
+		return
+	}
+	item.Price, item.StopPrice, item.StarTime = check.Price, check.StopPrice, origin.StarTime
+	list[idx] = item
+	a.sortEntrust(item.Mode)
+	a.recordAction(timestamp, ActionAmend, fmt.Sprintf("修改%s: %s → %s", item.Title(), origin.Desc(), item.Desc()))
+	return nil
+}
+
+// GetEntrust 根据编号获取委托单, 包括已成交, 撤销, 失效和被拒绝的委托单
+func (a *Account) GetEntrust(id int) (record Entrust, isExist bool) {
+	if list, idx := a.findEntrust(id); idx >= 0 {
+		return list[idx], true
+	}
+	for _, list := range [][]Entrust{a.Closed, a.Rejected} {
+		for _, entrust := range list {
+			if entrust.ID == id {
+				return entrust, true
+			}
+		}
+	}
+	return
+}
+
+// OpenEntrusts 获取所有仍在等待成交的委托单 (包括部分成交和未生效的括号单), 按编号排序
+func (a *Account) OpenEntrusts() (records []Entrust) {
+	for _, list := range [][]Entrust{a.SellEntrust, a.BuyEntrust} {
+		for _, entrust := range list {
+			if entrust.IsOpen() {
+				records = append(records, entrust)
+			}
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].ID < records[j].ID
+	})
+	return
+}
+
+// 检查委托单是否合法, 价格调整为最接近的合法价格
+// 当日失效的委托单价格超出当日涨跌停范围时拒绝; 跨交易日的委托单可能在之后的交易日成交, 成交时按当日的涨跌停价格检查
+func (a *Account) checkEntrust(item Entrust) (result Entrust, err error) {
+	result = item
+	actionType := ActionGiveUp
+	rule := a.GetRule()
+	if item.Mode != ModeBuy && item.Mode != ModeShell {
+		err = newTradError(ErrInvalidParams, "委托方向不合法: mode=%s", item.Mode)
+	} else if item.Vol <= 0 {
+		err = newTradError(ErrInvalidParams, "委托数量不合法: vol=%d", item.Vol)
+	} else if isLegal, ruleReason := rule.CheckVol(item.Mode, item.Vol, a.Balance.StockVol); !isLegal {
+		err = newTradError(ErrLotSize, "委托数量不合法: vol=%d %s", item.Vol, ruleReason)
+	} else if item.Kind == KindTrailing && item.TrailRate <= 0 && item.TrailAmount <= 0 {
+		err = newTradError(ErrInvalidParams, "跟踪止损单需要设置回撤比例或回撤金额: %+v", item)
+	}
+	if err == nil {
+		item.Price = rule.SnapPrice(item.Price)
+		item.StopPrice = rule.SnapPrice(item.StopPrice)
+		isDayEntrust := item.DeadTime > 0 && tradeDay(item.DeadTime) <= a.Limit.TradeDay
+		if isDayEntrust && (item.Kind == KindLimit || item.Kind == KindStopLimit) && !a.Limit.Contains(item.Price) {
+			err = newTradError(ErrPriceLimit, "委托价超出涨跌停范围: 委托价=%.3f 涨停价=%.3f 跌停价=%.3f", item.Price, a.Limit.Up, a.Limit.Down)
+			actionType = ActionLimit
+		}
+	}
+	if err != nil {
+		a.recordAction(item.StarTime, actionType, fmt.Sprintf("拒绝%s: %s", item.Title(), err))
+		return
+	}
+	item.Status = StatusPending
+	return item, nil
+}
+
+// 将未能创建的委托单记录到Rejected, 二选一委托和括号单中有一个不合法时全部拒绝
+func (a *Account) rejectEntrust(items ...Entrust) {
+	for _, item := range items {
+		item.Status, item.DealTime = StatusRejected, item.StarTime
+		a.Rejected = append(a.Rejected, item)
+	}
+}
+
+// 添加委托单并记录操作日志, 委托列表按价格排序
+func (a *Account) addEntrust(item Entrust) {
+	expireDesc := ""
+	if item.DeadTime > 0 {
+		expireDesc = fmt.Sprintf(" (%s截至)", TimeFormat(item.DeadTime))
+	}
+	if item.After > 0 {
+		expireDesc += " (括号单, 建仓成交后生效)"
+	}
+	a.recordAction(item.StarTime, ActionEntrust, fmt.Sprintf("创建%s, %s%s", item.Title(), item.Desc(), expireDesc))
+	if item.Mode == ModeBuy {
+		a.BuyEntrust = append(a.BuyEntrust, item)
+	}
+	if item.Mode == ModeShell {
+		a.SellEntrust = append(a.SellEntrust, item)
+	}
+	a.sortEntrust(item.Mode)
+}
+
+// 委托列表按价格排序, 买入委托价格从高到低, 卖出委托价格从低到高
+func (a *Account) sortEntrust(mode OpMode) {
+	if mode == ModeBuy {
+		sort.SliceStable(a.BuyEntrust, func(i, j int) bool {
+			return a.BuyEntrust[i].Price > a.BuyEntrust[j].Price
+		})
+	}
+	if mode == ModeShell {
+		sort.SliceStable(a.SellEntrust, func(i, j int) bool {
+			return a.SellEntrust[i].Price < a.SellEntrust[j].Price
+		})
+	}
+}
+
+// 结束等待中的委托单并记录操作日志, 未成交的建仓委托结束时一并结束其括号单
+func (a *Account) closeEntrust(list []Entrust, idx int, status EntrustStatus, timestamp int64, actionType ActionType, desc string) {
+	entrust := list[idx]
+	list[idx].Status, list[idx].DealTime = status, timestamp
+	a.recordAction(timestamp, actionType, fmt.Sprintf("%s%s: %s, 已成交=%d/%d", desc, entrust.Title(), entrust.Desc(), entrust.FilledVol, entrust.Vol))
+	if entrust.Group == 0 || entrust.After > 0 {
+		return
+	}
+	if entrust.FilledVol > 0 { // 建仓委托部分成交, 括号单按已成交份额生效
+		a.activateBracket(entrust, timestamp)
+		return
+	}
+	for _, children := range [][]Entrust{a.SellEntrust, a.BuyEntrust} {
+		for i, child := range children {
+			if child.IsOpen() && child.After == entrust.Group {
+				a.closeEntrust(children, i, status, timestamp, actionType, "建仓委托已"+status.Desc()+", 括号单")
+			}
+		}
+	}
+}
+
+// 将已结束的委托单从委托列表移动到Closed, 避免委托列表随交易次数不断变长
+// 使用新的切片保存仍在等待的委托单, 之前返回的委托单指针仍然有效
+func (a *Account) archiveEntrust() {
+	archive := func(list []Entrust) []Entrust {
+		open := make([]Entrust, 0, len(list))
+		for _, entrust := range list {
+			if entrust.IsOpen() {
+				open = append(open, entrust)
+			} else {
+				a.Closed = append(a.Closed, entrust)
+			}
+		}
+		return open
+	}
+	a.SellEntrust, a.BuyEntrust = archive(a.SellEntrust), archive(a.BuyEntrust)
+}
+
+// 根据编号查找委托单所在的列表和位置, 不存在时idx为-1
+func (a *Account) findEntrust(id int) (list []Entrust, idx int) {
+	for _, list = range [][]Entrust{a.SellEntrust, a.BuyEntrust} {
+		for i, entrust := range list {
+			if entrust.ID == id {
+				return list, i
+			}
+		}
+	}
+	return nil, -1
+}
+
+// 获取新的委托编号
+func (a *Account) nextEntrustID() int {
+	a.EntrustSeq++
+	return a.EntrustSeq
+}
+
+// 获取新的委托组编号
+func (a *Account) nextGroup() int {
+	group := 0
+	for _, list := range [][]Entrust{a.SellEntrust, a.BuyEntrust} {
+		for _, entrust := range list {
+			if entrust.Group > group {
+				group = entrust.Group
+			}
+		}
+	}
+	return group + 1
+}
+
+// ExecuteEntrust 按照节点内的价格路径执行第一个被触发的委托单, 返回对应的委托信息, 没有成交时mode为ModeWait
+func (a *Account) ExecuteEntrust(moment KLineNode) (mode OpMode, record *Entrust) {
+	mode = ModeWait
+	a.executePath(moment, func(fillMode OpMode, idx int) bool {
+		mode, record = fillMode, &a.entrustList(fillMode)[idx]
+		return true
+	})
+	return
+}
+
+// ExecuteEntrustPath 按照节点内的价格路径依次执行所有被触发的委托单, 每次成交后调用onFill, 返回成交次数
+// 回调中创建的委托单在价格继续经过委托价时同样可以在当前节点成交
+func (a *Account) ExecuteEntrustPath(moment KLineNode, onFill FillCallback) (fillCount int) {
+	return a.executePath(moment, func(mode OpMode, idx int) bool {
+		if onFill != nil {
+			onFill(mode, a.entrustList(mode)[idx])
+		}
+		return false
+	})
+}
+
+// 沿价格路径执行委托单, onFill返回true时停止执行
+func (a *Account) executePath(moment KLineNode, onFill func(mode OpMode, idx int) (stop bool)) (fillCount int) {
+	a.archiveEntrust()
+	a.expireEntrust(moment.Timestamp)
+	if a.Limit.IsLocked(moment) { // 一字涨跌停时不成交
+		if _, isExist := a.triggeredEntrust(moment, ModeWait); isExist {
+			a.recordAction(moment.Timestamp, ActionLimit, fmt.Sprintf("一字涨跌停,委托单不成交: 价格=%.3f 涨停价=%.3f 跌停价=%.3f",
+				moment.End, a.Limit.Up, a.Limit.Down))
+		}
+		return
+	}
+	pricePath := a.PricePath
+	if pricePath == nil {
+		pricePath = &OHLCPath{}
+	}
+	path := pricePath.Path(moment)
+	blocked := make(map[int]bool) // 当前节点内无法成交的委托单编号
+	cur := path[0]
+	a.updateTrailing(cur)
+	for _, target := range path {
+		for fillCount < maxPathFills {
+			mode, idx := a.nextTriggered(cur, target, blocked)
+			if idx < 0 {
+				break
+			}
+			list := a.entrustList(mode)
+			entrust := list[idx]
+			trigger, direction := entrust.trigger()
+			if (direction > 0 && trigger > cur) || (direction < 0 && trigger < cur) {
+				cur = trigger // 价格沿路径运行到触发价
+				a.updateTrailing(cur)
+			}
+			if entrust.Kind == KindStopLimit && !entrust.Triggered { // 触发后转为限价条件单
+				list[idx].Triggered = true
+				a.recordAction(moment.Timestamp, ActionStopLimit, fmt.Sprintf("止损限价单触发, 触发价=%.3f 转为限价委托: %s", entrust.StopPrice, list[idx].Desc()))
+				continue
+			}
+			if !a.fillEntrust(mode, idx, cur, moment) {
+				blocked[entrust.ID] = true
+				continue
+			}
+			fillCount++
+			if onFill(mode, idx) {
+				return
+			}
+		}
+		cur = target
+		a.updateTrailing(cur)
+	}
+	if fillCount >= maxPathFills {
+		log.Warning("too many fills in one moment, stop executing: time=%s fillCount=%d", moment.TimeDesc, fillCount)
+	}
+	for _, mode := range []OpMode{ModeShell, ModeBuy} { // 记录因禁止买入或卖出而没有执行的委托单
+		if entrust, isExist := a.triggeredEntrust(moment, mode); isExist {
+			a.checkLock(mode, entrust.Price, moment)
+		}
+	}
+	return
+}
+
+// 获取价格从cur运行到target的过程中下一个被触发的委托单: 优先处理按当前价格已经触发的委托单(卖出优先), 其次按价格经过的顺序
+func (a *Account) nextTriggered(cur float64, target float64, blocked map[int]bool) (mode OpMode, idx int) {
+	mode, idx = ModeWait, -1
+	best := 0.0
+	pick := func(m OpMode, i int, price float64, direction int) {
+		if idx < 0 || (direction > 0 && price < best) || (direction < 0 && price > best) {
+			mode, idx, best = m, i, price
+		}
+	}
+	scan := func(m OpMode, match func(price float64, direction int) bool) {
+		if a.isLocked(m) {
+			return
+		}
+		for i, entrust := range a.entrustList(m) {
+			if !entrust.IsActive() {
+				continue
+			}
+			if price, direction := entrust.trigger(); match(price, direction) && !blocked[entrust.ID] {
+				pick(m, i, price, direction)
+			}
+		}
+	}
+	triggered := func(price float64, direction int) bool {
+		return (direction > 0 && price <= cur) || (direction < 0 && price >= cur)
+	}
+	for _, m := range []OpMode{ModeShell, ModeBuy} {
+		if scan(m, triggered); idx >= 0 {
+			return
+		}
+	}
+	for _, m := range []OpMode{ModeShell, ModeBuy} {
+		scan(m, func(price float64, direction int) bool {
+			if target > cur {
+				return direction > 0 && price > cur && price <= target
+			}
+			return direction < 0 && price < cur && price >= target
+		})
+	}
+	return
+}
+
+// 执行指定的委托单, cur为触发时的价格, 返回是否有份额成交
+func (a *Account) fillEntrust(mode OpMode, idx int, cur float64, moment KLineNode) bool {
+	list := a.entrustList(mode)
+	entrust := list[idx]
+	if sellable := a.SellableVol(moment.Timestamp); mode == ModeShell && entrust.RemainVol() > sellable { // 当日买入的份额不能卖出
+		err := newTradError(ErrSettlement, "T+1限制,可卖份额不足,等待下一交易日成交: %s 可卖份额=%d 持有份额=%d",
+			entrust.Title(), sellable, a.Balance.StockVol)
+		a.recordAction(moment.Timestamp, ActionGiveUp, err.Error())
+		return false
+	}
+	prize := entrust.Price
+	if entrust.Kind == KindStop || entrust.Kind == KindTrailing { // 按触发时的价格成交
+		prize = a.LegalPrice(cur)
+		actionType := map[bool]ActionType{true: ActionStopLoss, false: ActionStopBuy}[mode == ModeShell]
+		if entrust.Kind == KindTrailing {
+			actionType = ActionTrailing
+		}
+		a.recordAction(moment.Timestamp, actionType, fmt.Sprintf("%s触发: 触发价=%.3f 成交价=%.3f 份额=%d", entrust.KindDesc(), entrust.TriggerPrice(), prize, entrust.RemainVol()))
+	}
+	dealVol, err := a.deal(mode, prize, entrust.RemainVol(), moment)
+	if err != nil {
+		return false
+	}
+	list[idx].FilledVol += dealVol
+	list[idx].Status = StatusPartial
+	if list[idx].RemainVol() <= 0 {
+		list[idx].Status, list[idx].DealTime = StatusFilled, moment.Timestamp
+	}
+	a.recordAction(moment.Timestamp, ActionFilled, fmt.Sprintf("%s%s: 成交价=%.3f 成交份额=%d 已成交=%d/%d",
+		list[idx].Title(), list[idx].Status.Desc(), prize, dealVol, list[idx].FilledVol, list[idx].Vol))
+	a.settleGroup(list[idx], moment.Timestamp)
+	return true
+}
+
+// 委托单成交后撤销同组的其他委托单, 全部成交后激活等待该组成交的括号单
+func (a *Account) settleGroup(filled Entrust, timestamp int64) {
+	if filled.Group == 0 {
+		return
+	}
+	for _, list := range [][]Entrust{a.SellEntrust, a.BuyEntrust} {
+		for i, entrust := range list {
+			if !entrust.IsActive() {
+				continue
+			}
+			if entrust.Group == filled.Group && entrust.ID != filled.ID {
+				a.closeEntrust(list, i, StatusCancelled, timestamp, ActionOCO, "二选一委托已成交, 撤销")
+			}
+		}
+	}
+	if filled.Status == StatusFilled {
+		a.activateBracket(filled, timestamp)
+	}
+}
+
+// 激活等待建仓委托成交的括号单, 建仓委托部分成交后结束时括号单份额按成交比例缩减 (向下取整到整手)
+func (a *Account) activateBracket(entry Entrust, timestamp int64) {
+	lotSize := a.GetRule().LotSize
+	for _, list := range [][]Entrust{a.SellEntrust, a.BuyEntrust} {
+		for i, entrust := range list {
+			if !entrust.IsOpen() || entrust.After != entry.Group {
+				continue
+			}
+			list[i].After, list[i].StarTime = 0, timestamp
+			if entry.FilledVol < entry.Vol {
+				vol := entrust.Vol * entry.FilledVol / entry.Vol
+				if lotSize > 1 && vol != entry.FilledVol {
+					vol = vol / lotSize * lotSize
+				}
+				if list[i].Vol = vol; vol <= 0 {
+					a.closeEntrust(list, i, StatusCancelled, timestamp, ActionBracket, "建仓委托成交份额不足, 撤销括号单")
+					continue
+				}
+			}
+			if entrust.Kind == KindTrailing {
+				list[i].Extreme = entry.Price
+			}
+			a.recordAction(timestamp, ActionBracket, fmt.Sprintf("建仓委托已成交%d份, %s生效: %s", entry.FilledVol, list[i].Title(), list[i].Desc()))
+		}
+	}
+}
+
+// 将已过期的委托单标记为失效, 未生效的括号单随建仓委托一起失效
+func (a *Account) expireEntrust(timestamp int64) {
+	for _, list := range [][]Entrust{a.SellEntrust, a.BuyEntrust} {
+		for i, entrust := range list {
+			if entrust.IsOpen() && entrust.DeadTime > 0 && entrust.DeadTime < timestamp {
+				a.closeEntrust(list, i, StatusExpired, entrust.DeadTime, ActionExpire, "委托到期, ")
+			}
+		}
+	}
+}
+
+// 更新跟踪止损单的最高价或最低价
+func (a *Account) updateTrailing(cur float64) {
+	for _, list := range [][]Entrust{a.SellEntrust, a.BuyEntrust} {
+		for i, entrust := range list {
+			if entrust.Kind != KindTrailing || !entrust.IsActive() {
+				continue
+			}
+			if (entrust.Mode == ModeShell && cur > entrust.Extreme) || (entrust.Mode == ModeBuy && cur < entrust.Extreme) {
+				list[i].Extreme = cur
+				list[i].Price = list[i].TriggerPrice()
+			}
+		}
+	}
+}
+
+// 获取节点价格范围内达到触发价格且仍有效的委托单, mode为ModeWait时检查买卖两个方向
+func (a *Account) triggeredEntrust(moment KLineNode, mode OpMode) (record Entrust, isExist bool) {
+	for _, list := range [][]Entrust{a.SellEntrust, a.BuyEntrust} {
+		for _, entrust := range list {
+			if !entrust.IsActive() || (mode != ModeWait && entrust.Mode != mode) {
+				continue
+			}
+			if price, direction := entrust.trigger(); (direction > 0 && moment.Top >= price) || (direction < 0 && moment.Bottom <= price) {
+				return entrust, true
+			}
+		}
+	}
+	return
+}
+
+func (a *Account) entrustList(mode OpMode) []Entrust {
+	if mode == ModeShell {
+		return a.SellEntrust
+	}
+	return a.BuyEntrust
+}
+
+// IsOpen 委托单是否仍在等待成交 (未全部成交, 未撤销, 未失效)
+func (e Entrust) IsOpen() bool {
+	return e.Status == StatusPending || e.Status == StatusPartial
+}
+
+// IsActive 委托单是否仍在等待成交且已经生效 (括号单需要等待建仓委托成交)
+func (e Entrust) IsActive() bool {
+	return e.IsOpen() && e.After == 0
+}
+
+// Title 委托单类型和编号, 例如 条件单#3
+func (e Entrust) Title() string {
+	return fmt.Sprintf("%s#%d", e.KindDesc(), e.ID)
+}
+
+// StatusDesc 委托状态描述
+func (e Entrust) StatusDesc() string {
+	return e.Status.Desc()
+}
+
+// TriggerPrice 获取委托单的触发价
+func (e Entrust) TriggerPrice() float64 {
+	switch e.Kind {
+	case KindStop, KindStopLimit:
+		return e.StopPrice
+	case KindTrailing:
+		offset := e.TrailAmount
+		if e.TrailRate > 0 {
+			offset = e.Extreme * e.TrailRate
+		}
+		if e.Mode == ModeShell {
+			return e.Extreme - offset
+		}
+		return e.Extreme + offset
+	}
+	return e.Price
+}
+
+// 获取委托单当前的触发价和触发方向: 1表示价格上涨到触发价时触发, -1表示下跌到触发价时触发
+func (e Entrust) trigger() (price float64, direction int) {
+	rising := e.Mode == ModeShell // 限价卖出单在价格上涨时触发
+	if e.Kind == KindStop || e.Kind == KindTrailing || (e.Kind == KindStopLimit && !e.Triggered) {
+		rising = !rising
+	}
+	price = e.TriggerPrice()
+	if e.Kind == KindStopLimit && e.Triggered {
+		price = e.Price
+	}
+	if rising {
+		return price, 1
+	}
+	return price, -1
+}
+
+// KindDesc 委托单类型描述
+func (e Entrust) KindDesc() string {
+	switch e.Kind {
+	case KindStop:
+		if e.Mode == ModeShell {
+			return "止损单"
+		}
+		return "突破买入单"
+	case KindStopLimit:
+		return "止损限价单"
+	case KindTrailing:
+		return "跟踪止损单"
+	}
+	return "条件单"
+}
+
+// Desc 委托单触发条件描述
+func (e Entrust) Desc() string {
+	switch e.Kind {
+	case KindStop:
+		if e.Mode == ModeShell {
+			return fmt.Sprintf("价格跌破 %.3f 时卖出 %d 份", e.StopPrice, e.Vol)
+		}
+		return fmt.Sprintf("价格突破 %.3f 时买入 %d 份", e.StopPrice, e.Vol)
+	case KindStopLimit:
+		return fmt.Sprintf("价格到达 %.3f 后以 %.3f %s %d 份", e.StopPrice, e.Price, e.Mode, e.Vol)
+	case KindTrailing:
+		trail := fmt.Sprintf("%.3f元", e.TrailAmount)
+		if e.TrailRate > 0 {
+			trail = fmt.Sprintf("%.2f%%", e.TrailRate*100)
+		}
+		if e.Mode == ModeBuy {
+			return fmt.Sprintf("价格从最低 %.3f 反弹 %s 时买入 %d 份", e.Extreme, trail, e.Vol)
+		}
+		return fmt.Sprintf("价格从最高 %.3f 回撤 %s 时卖出 %d 份", e.Extreme, trail, e.Vol)
+	}
+	if e.Mode == ModeBuy {
+		return fmt.Sprintf("价格下破 %.2f 时买入 %d 份", e.Price, e.Vol)
+	}
+	return fmt.Sprintf("价格上穿 %.2f 时卖出 %d 份", e.Price, e.Vol)
+}
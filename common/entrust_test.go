@@ -0,0 +1,146 @@
+package common
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestOrderTypes(t *testing.T) {
+	newAccount := func() *Account {
+		account := mockAccount("Order", 100000)
+		account.T0, account.Balance.StockVol = true, 1000
+		return account
+	}
+	lastAction := func(account *Account, mode ActionType) bool {
+		for _, action := range account.ActionLog {
+			if action.Mode == mode {
+				return true
+			}
+		}
+		return false
+	}
+	// 阴线 开盘→最高→最低→收盘: 10 → 10.5 → 9.5 → 9.6
+	moment := mockNode("2022-06-01", 10)
+	moment.End = 9.6
+	ts := moment.Timestamp
+
+	// 止损卖出和突破买入
+	account := newAccount()
+	account.PlaceEntrust(NewStopEntrust(ModeShell, 9.8, 100, ts, 0))
+	account.PlaceEntrust(NewStopEntrust(ModeBuy, 10.2, 100, ts, 0))
+	var fills []float64
+	account.ExecuteEntrustPath(moment, func(mode OpMode, record Entrust) {
+		fills = append(fills, record.StopPrice)
+	})
+	if len(fills) != 2 || fills[0] != 10.2 || fills[1] != 9.8 || !lastAction(account, ActionStopLoss) || !lastAction(account, ActionStopBuy) {
+		t.Fatalf("unexpect stop fills: %v", fills)
+	}
+
+	// 跟踪止损: 最高价10.5回撤5%即9.975时卖出
+	account = newAccount()
+	account.PlaceEntrust(NewTrailingEntrust(ModeShell, 10, 0.05, 0, 100, ts, 0))
+	if mode, _ := account.ExecuteEntrust(moment); mode != ModeShell || account.TradLog[0].Prize != 9.98 || !lastAction(account, ActionTrailing) {
+		t.Fatalf("unexpect trailing fill: mode=%s log=%+v", mode, account.TradLog)
+	}
+
+	// 止损限价: 跌破9.8后以9.7卖出
+	account = newAccount()
+	account.PlaceEntrust(NewStopLimitEntrust(ModeShell, 9.8, 9.7, 100, ts, 0))
+	if mode, _ := account.ExecuteEntrust(moment); mode != ModeShell || account.TradLog[0].Prize != 9.7 || !lastAction(account, ActionStopLimit) {
+		t.Fatalf("unexpect stop limit fill: mode=%s log=%+v", mode, account.TradLog)
+	}
+
+	// 二选一: 止盈先成交, 止损被撤销
+	account = newAccount()
+	account.PlaceOCO(NewLimitEntrust(ModeShell, 10.4, 100, ts, 0), NewStopEntrust(ModeShell, 9.7, 100, ts, 0))
+	if n := account.ExecuteEntrustPath(moment, nil); n != 1 || account.Balance.StockVol != 900 || !lastAction(account, ActionOCO) {
+		t.Fatalf("unexpect oco result: fills=%d vol=%d", n, account.Balance.StockVol)
+	}
+
+	// 括号单: 建仓成交后止盈止损生效, 下跌触发止损
+	account = newAccount()
+	account.PlaceBracket(NewLimitEntrust(ModeBuy, 10.3, 200, ts, 0),
+		NewLimitEntrust(ModeShell, 11, 0, ts, 0), NewStopEntrust(ModeShell, 9.9, 0, ts, 0))
+	if n := account.ExecuteEntrustPath(moment, nil); n != 2 || account.Balance.StockVol != 1000 || !lastAction(account, ActionBracket) {
+		t.Fatalf("unexpect bracket result: fills=%d vol=%d log=%+v", n, account.Balance.StockVol, account.TradLog)
+	}
+	for _, entrust := range account.SellEntrust {
+		if entrust.DealTime == 0 {
+			t.Fatalf("expect take profit cancelled: %+v", entrust)
+		}
+	}
+
+	// 括号单: 建仓委托部分成交后撤销, 止盈止损按已成交份额生效
+	account = newAccount()
+	account.Execution = &VolumeFill{Rate: 0.1, Unit: 100}
+	moment.Vol = 25
+	ids, _ := account.PlaceBracket(NewLimitEntrust(ModeBuy, 10.3, 400, ts, 0),
+		NewLimitEntrust(ModeShell, 11, 0, ts, 0), NewStopEntrust(ModeShell, 9, 0, ts, 0))
+	if n := account.ExecuteEntrustPath(moment, nil); n != 1 || account.Balance.StockVol != 1200 {
+		t.Fatalf("expect partial entry fill: fills=%d vol=%d", n, account.Balance.StockVol)
+	}
+	if err := account.CancelEntrust(ids[0], ts); err != nil {
+		t.Fatalf("cancel fail: err=%v", err)
+	}
+	for _, id := range ids[1:] {
+		if record, _ := account.GetEntrust(id); !record.IsActive() || record.Vol != 200 {
+			t.Fatalf("expect bracket activated with filled vol: %+v", record)
+		}
+	}
+}
+
+func TestEntrustLifecycle(t *testing.T) {
+	moment := mockNode("2022-06-01", 10)
+	moment.Vol = 25
+	ts, next := moment.Timestamp, mockNode("2022-06-02", 10).Timestamp
+	account := mockAccount("Lifecycle", 100000)
+	account.Balance.StockVol, account.Execution = 1000, &VolumeFill{Rate: 0.1, Unit: 100}
+	id1, _ := account.CreateEntrust(ModeBuy, 9.6, 400, ts, next)
+	id2, _ := account.CreateEntrust(ModeBuy, 9.0, 100, ts, 0)
+	id3, err := account.CreateEntrust(ModeShell, 10.8, 150, ts, 0)
+	if id1 != 1 || id2 != 2 || id3 != 3 || err == nil {
+		t.Fatalf("unexpect ids: %d %d %d err=%v", id1, id2, id3, err)
+	}
+	if record, _ := account.GetEntrust(id3); record.Status != StatusRejected {
+		t.Fatalf("expect rejected entrust: %+v", record)
+	}
+	id4, _ := account.CreateEntrust(ModeShell, 10.8, 100, ts, 0)
+
+	// 修改委托价后按新价格排序, 撤销后不再成交
+	if err = account.AmendEntrust(id2, 9.7, 0, ts); err != nil || account.BuyEntrust[0].ID != id2 {
+		t.Fatalf("amend fail: err=%v list=%+v", err, account.BuyEntrust)
+	}
+	if err = account.AmendEntrust(id2, 0, 50, ts); !errors.Is(err, ErrLotSize) {
+		t.Fatalf("expect odd lot amend rejected: err=%v", err)
+	}
+	if err = account.CancelEntrust(id2, ts); err != nil {
+		t.Fatalf("cancel fail: err=%v", err)
+	}
+	if err = account.CancelEntrust(id2, ts); !errors.Is(err, ErrEntrustClosed) {
+		t.Fatalf("expect cancel twice fail: err=%v", err)
+	}
+	if open := account.OpenEntrusts(); len(open) != 2 || open[0].ID != id1 || open[1].ID != id4 {
+		t.Fatalf("unexpect open entrusts: %+v", open)
+	}
+
+	// 成交量限制导致部分成交, 剩余份额到期后失效
+	if mode, record := account.ExecuteEntrust(moment); mode != ModeBuy || record.ID != id1 || record.Status != StatusPartial {
+		t.Fatalf("expect partial fill: mode=%s record=%+v", mode, record)
+	}
+	moment.Timestamp, moment.TimeDesc = next+86400, "2022-06-03"
+	account.ExecuteEntrust(moment)
+	if record, _ := account.GetEntrust(id1); record.Status != StatusExpired || record.FilledVol != 200 || record.DealTime != next {
+		t.Fatalf("expect expired entrust: %+v", record)
+	}
+	if n := account.CancelAll(ModeShell, moment.Timestamp); n != 1 || len(account.OpenEntrusts()) != 0 {
+		t.Fatalf("unexpect cancel all: n=%d open=%+v", n, account.OpenEntrusts())
+	}
+	counter := make(map[ActionType]int)
+	for _, action := range account.ActionLog {
+		counter[action.Mode]++
+	}
+	if counter[ActionEntrust] != 3 || counter[ActionAmend] != 1 || counter[ActionCancel] != 2 ||
+		counter[ActionFilled] != 1 || counter[ActionExpire] != 1 || counter[ActionGiveUp] != 2 {
+		t.Fatalf("unexpect action log: %v", counter)
+	}
+}
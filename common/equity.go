@@ -0,0 +1,78 @@
+package common
+
+// EquitySampling 资产曲线的采样频率
+type EquitySampling int
+
+const (
+	SampleNone EquitySampling = iota // 不记录资产曲线
+	SampleBar                        // 每个节点结束时记录一次
+	SampleDay                        // 每个交易日记录一次, 为当日最后一个节点结束时的状态
+)
+
+// EquityPoint 资产曲线上的一个点, 记录节点结束时的账号状态
+type EquityPoint struct {
+	Timestamp   int64   `json:"timestamp"`   // 节点时间
+	Price       float64 `json:"price"`       // 最新价格 (组合为0)
+	Cash        float64 `json:"cash"`        // 可用现金
+	StockVol    int     `json:"stockVol"`    // 持有份额
+	MarketValue float64 `json:"marketValue"` // 持仓市值
+	TotalValue  float64 `json:"totalValue"`  // 总资产 (融资融券账户为净资产)
+	CostRMB     float64 `json:"costRmb"`     // 持仓成本
+	BuyEntrust  int     `json:"buyEntrust"`  // 等待成交的买入委托数量
+	SellEntrust int     `json:"sellEntrust"` // 等待成交的卖出委托数量
+}
+
+// RecordEquity 按照账号的采样频率记录当前状态到资产曲线, 需要在每个节点执行策略之后调用
+func (a *Account) RecordEquity() {
+	if a.Sampling == SampleNone || a.LastPrize == nil {
+		return
+	}
+	point := EquityPoint{
+		Timestamp:   a.LastPrize.Timestamp,
+		Price:       a.LastPrize.End,
+		Cash:        a.Cash(),
+		StockVol:    a.Balance.StockVol,
+		MarketValue: a.LastPrize.End * float64(a.Balance.StockVol),
+		TotalValue:  a.NetAsset(),
+		CostRMB:     a.Balance.CostRMB,
+		BuyEntrust:  countOpen(a.BuyEntrust),
+		SellEntrust: countOpen(a.SellEntrust),
+	}
+	a.Equity = appendEquity(a.Equity, a.Sampling, point)
+}
+
+// RecordEquity 按照组合的采样频率记录当前状态到资产曲线, 持仓和委托为各子账号之和
+func (p *Portfolio) RecordEquity(timestamp int64) {
+	if p.Sampling == SampleNone {
+		return
+	}
+	point := EquityPoint{Timestamp: timestamp, Cash: p.BalanceRMB, MarketValue: p.MarketValue()}
+	point.TotalValue = point.Cash + point.MarketValue
+	for _, symbol := range p.Symbols() {
+		account := p.Accounts[symbol]
+		point.StockVol += account.Balance.StockVol
+		point.CostRMB += account.Balance.CostRMB
+		point.BuyEntrust += countOpen(account.BuyEntrust)
+		point.SellEntrust += countOpen(account.SellEntrust)
+	}
+	p.Equity = appendEquity(p.Equity, p.Sampling, point)
+}
+
+// 按日采样时同一交易日只保留最后一个点
+func appendEquity(points []EquityPoint, sampling EquitySampling, point EquityPoint) []EquityPoint {
+	if n := len(points); sampling == SampleDay && n > 0 && tradeDay(points[n-1].Timestamp) == tradeDay(point.Timestamp) {
+		points[n-1] = point
+		return points
+	}
+	return append(points, point)
+}
+
+// 统计等待成交的委托数量
+func countOpen(list []Entrust) (count int) {
+	for _, entrust := range list {
+		if entrust.IsOpen() {
+			count++
+		}
+	}
+	return
+}
@@ -0,0 +1,41 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestRecordEquity(t *testing.T) {
+	nodes := []KLineNode{mockNode("2022-06-01 10:00", 10), mockNode("2022-06-01 14:00", 10.5), mockNode("2022-06-02 10:00", 11)}
+	record := func(sampling EquitySampling) *Account {
+		account := mockAccount("Equity", 100000)
+		account.Sampling = sampling
+		for i, node := range nodes {
+			account.UpdateStat(node)
+			if i == 0 {
+				account.Trad(ModeBuy, 10, 1000, node)
+				account.CreateEntrust(ModeShell, 12, 500, node.Timestamp, 0)
+			}
+			account.RecordEquity()
+		}
+		return account
+	}
+
+	// 按节点采样, 现金+持仓市值=总资产
+	account := record(SampleBar)
+	if len(account.Equity) != len(nodes) {
+		t.Fatalf("unexpect bar equity: n=%d", len(account.Equity))
+	}
+	want := EquityPoint{Timestamp: nodes[2].Timestamp, Price: 11, Cash: 90000, StockVol: 1000, MarketValue: 11000, TotalValue: 101000, CostRMB: 10000, SellEntrust: 1}
+	if last := account.Equity[2]; last != want {
+		t.Fatalf("unexpect last point: got=%+v want=%+v", last, want)
+	}
+
+	// 按日采样, 每个交易日保留最后一个节点的状态
+	daily := record(SampleDay)
+	if len(daily.Equity) != 2 || daily.Equity[0] != account.Equity[1] || daily.Equity[1] != want {
+		t.Fatalf("unexpect daily equity: %+v", daily.Equity)
+	}
+	if none := record(SampleNone); len(none.Equity) != 0 {
+		t.Fatalf("expect no equity curve by default: n=%d", len(none.Equity))
+	}
+}
@@ -0,0 +1,39 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+)
+
+// 交易失败的类型, 可以通过errors.Is判断Trad或委托操作返回的错误
+var (
+	ErrInvalidParams      = errors.New("invalid params")         // 参数错误
+	ErrInsufficientCash   = errors.New("insufficient cash")      // 余额不足
+	ErrInsufficientShares = errors.New("insufficient shares")    // 持有份额不足
+	ErrLocked             = errors.New("trade locked")           // 买入或卖出被禁止 (Setting.BuyLock/SellLock)
+	ErrLotSize            = errors.New("illegal lot size")       // 委托数量不是整手
+	ErrPriceTick          = errors.New("illegal price tick")     // 委托价不是最小变动价位的整数倍
+	ErrPriceLimit         = errors.New("price limit")            // 超出涨跌停范围或一字涨跌停
+	ErrSettlement         = errors.New("t+1 settlement")         // T+1制度下当日买入的份额不能卖出
+	ErrNoLiquidity        = errors.New("insufficient liquidity") // 成交量不足
+	ErrEntrustNotFound    = errors.New("entrust not found")      // 委托单不存在
+	ErrEntrustClosed      = errors.New("entrust closed")         // 委托单已成交, 撤销或失效
+)
+
+// TradError 交易失败的原因, Kind为上面定义的失败类型, Reason为具体描述
+type TradError struct {
+	Kind   error
+	Reason string
+}
+
+func (e *TradError) Error() string {
+	return e.Reason
+}
+
+func (e *TradError) Unwrap() error {
+	return e.Kind
+}
+
+func newTradError(kind error, format string, args ...interface{}) error {
+	return &TradError{Kind: kind, Reason: fmt.Sprintf(format, args...)}
+}
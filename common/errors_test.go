@@ -0,0 +1,45 @@
+package common
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTradError(t *testing.T) {
+	moment := mockNode("2022-06-01", 10)
+	ts := moment.Timestamp
+	account := mockAccount("Error", 10000)
+	account.T0, account.Balance.StockVol = true, 500
+	if err := account.Trad(ModeWait, 10, 100, moment); !errors.Is(err, ErrInvalidParams) {
+		t.Fatalf("expect invalid params: err=%v", err)
+	}
+	if err := account.Trad(ModeBuy, 10, 1100, moment); !errors.Is(err, ErrInsufficientCash) {
+		t.Fatalf("expect insufficient cash: err=%v", err)
+	}
+	if err := account.Trad(ModeShell, 10, 600, moment); !errors.Is(err, ErrInsufficientShares) {
+		t.Fatalf("expect insufficient shares: err=%v", err)
+	}
+
+	// 禁止卖出时交易和委托单都不成交, 并记录放弃交易的原因
+	account.Setting.SellLock = true
+	err := account.Trad(ModeShell, 10, 100, moment)
+	var tradErr *TradError
+	if !errors.Is(err, ErrLocked) || !errors.As(err, &tradErr) || tradErr.Reason == "" {
+		t.Fatalf("expect sell locked: err=%v", err)
+	}
+	if n := len(account.ActionLog); account.ActionLog[n-1].Mode != ActionGiveUp || account.ActionLog[n-1].Desc != err.Error() {
+		t.Fatalf("expect lock rejection logged: %+v", account.ActionLog[n-1])
+	}
+	account.CreateEntrust(ModeShell, 10.2, 100, ts, 0)
+	if mode, _ := account.ExecuteEntrust(moment); mode != ModeWait || account.Balance.StockVol != 500 || len(account.TradLog) != 0 {
+		t.Fatalf("expect locked entrust not executed: mode=%s vol=%d", mode, account.Balance.StockVol)
+	}
+	account.Setting.SellLock = false
+	if err = account.Trad(ModeShell, 10, 100, moment); err != nil {
+		t.Fatalf("expect sell ok after unlock: err=%v", err)
+	}
+	account.Setting.BuyLock = true
+	if err = account.Trad(ModeBuy, 10, 100, moment); !errors.Is(err, ErrLocked) || account.Balance.StockVol != 400 {
+		t.Fatalf("expect buy locked: err=%v vol=%d", err, account.Balance.StockVol)
+	}
+}
@@ -0,0 +1,77 @@
+package common
+
+import (
+	"math"
+)
+
+// ExecutionModel 成交模型, 根据委托和k线节点计算实际的成交价格和成交份额
+// 返回的成交份额小于委托份额时为部分成交, 为0时不成交
+type ExecutionModel interface {
+	Fill(mode OpMode, prize float64, vol int, moment KLineNode) (dealPrize float64, dealVol int)
+}
+
+// FillCommitter 需要记录实际成交的成交模型, 交易通过全部检查并成交后调用Commit
+// Fill计算的份额可能因为整手取整或余额不足等原因没有成交, 不能在Fill中记录
+type FillCommitter interface {
+	Commit(moment KLineNode, dealVol int)
+}
+
+// ExactFill 按委托价全部成交
+type ExactFill struct{}
+
+// Fill 按委托价全部成交
+func (e *ExactFill) Fill(mode OpMode, prize float64, vol int, moment KLineNode) (dealPrize float64, dealVol int) {
+	return prize, vol
+}
+
+// SlippageFill 按固定金额或比例的滑点成交, 买入价上调, 卖出价下调, 成交价不超出节点的价格区间
+type SlippageFill struct {
+	Fixed   float64 `json:"fixed"`   // 固定滑点 (元)
+	Percent float64 `json:"percent"` // 比例滑点, 例如0.1%为0.001
+}
+
+// Fill 按滑点调整成交价后全部成交
+func (s *SlippageFill) Fill(mode OpMode, prize float64, vol int, moment KLineNode) (dealPrize float64, dealVol int) {
+	slippage := s.Fixed + prize*s.Percent
+	if mode == ModeBuy {
+		return math.Min(prize+slippage, math.Max(moment.Top, prize)), vol
+	}
+	return math.Max(prize-slippage, math.Min(moment.Bottom, prize)), vol
+}
+
+// VolumeFill 按节点成交量的一定比例限制成交份额, 同一节点的多次成交共享额度, 未成交部分保留在委托单中
+type VolumeFill struct {
+	Rate float64 `json:"rate"` // 最大参与比例, 例如节点成交量的10%为0.1
+	Unit int     `json:"unit"` // 成交量单位对应的份额, 东方财富数据的成交量以手为单位, 为100 (0按1处理)
+
+	timestamp int64 // 当前节点时间
+	used      int   // 当前节点已成交份额
+}
+
+// Fill 按委托价成交, 成交份额不超过节点剩余的可参与份额
+func (v *VolumeFill) Fill(mode OpMode, prize float64, vol int, moment KLineNode) (dealPrize float64, dealVol int) {
+	unit, used := v.Unit, v.used
+	if unit <= 0 {
+		unit = 1
+	}
+	if v.timestamp != moment.Timestamp {
+		used = 0
+	}
+	available := int(math.Floor(moment.Vol*float64(unit)*v.Rate)) - used
+	if available <= 0 {
+		return prize, 0
+	}
+	dealVol = vol
+	if dealVol > available {
+		dealVol = available
+	}
+	return prize, dealVol
+}
+
+// Commit 累计当前节点已成交的份额
+func (v *VolumeFill) Commit(moment KLineNode, dealVol int) {
+	if v.timestamp != moment.Timestamp {
+		v.timestamp, v.used = moment.Timestamp, 0
+	}
+	v.used += dealVol
+}
@@ -0,0 +1,49 @@
+package common
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExecutionModel(t *testing.T) {
+	moment := mockNode("2022-06-01", 10)
+	moment.Vol = 25
+	ts, next := moment.Timestamp, mockNode("2022-06-02", 10).Timestamp
+	account := mockAccount("Execution", 100000)
+	account.Execution = &SlippageFill{Fixed: 0.01, Percent: 0.001}
+	account.Trad(ModeBuy, 10, 100, moment)
+	if r := account.TradLog[0]; r.Prize != 10.02 || r.EntrustPrize != 10 || r.Vol != 100 {
+		t.Fatalf("unexpect slippage fill: %+v", r)
+	}
+
+	// 每个节点最多成交节点成交量的10%, 剩余部分保留在委托单中
+	account.Execution = &VolumeFill{Rate: 0.1, Unit: 100}
+	account.CreateEntrust(ModeBuy, 10, 400, ts, 0)
+	if mode, record := account.ExecuteEntrust(moment); mode != ModeBuy || record.FilledVol != 200 || record.DealTime != 0 {
+		t.Fatalf("expect partial fill: mode=%s record=%+v", mode, record)
+	}
+	if r := account.TradLog[1]; r.Vol != 200 || r.EntrustVol != 400 {
+		t.Fatalf("unexpect partial trad log: %+v", r)
+	}
+	if mode, _ := account.ExecuteEntrust(moment); mode != ModeWait {
+		t.Fatalf("expect volume used up in same bar")
+	}
+	moment.Timestamp, moment.TimeDesc = next, "2022-06-02"
+	if mode, record := account.ExecuteEntrust(moment); mode != ModeBuy || record.FilledVol != 400 || record.DealTime != next {
+		t.Fatalf("expect remainder filled on next bar: mode=%s record=%+v", mode, record)
+	}
+	if account.Balance.StockVol != 500 {
+		t.Fatalf("unexpect vol: %d", account.Balance.StockVol)
+	}
+
+	// 余额不足没有成交时不占用节点的成交额度
+	poor := mockAccount("Volume", 1000)
+	poor.Execution = &VolumeFill{Rate: 0.1, Unit: 100}
+	if err := poor.Trad(ModeBuy, 10, 200, moment); !errors.Is(err, ErrInsufficientCash) {
+		t.Fatalf("expect insufficient cash: err=%v", err)
+	}
+	poor.Balance.BalanceRMB = 100000
+	if err := poor.Trad(ModeBuy, 10, 200, moment); err != nil || poor.Balance.StockVol != 200 {
+		t.Fatalf("expect volume not used by rejected trade: err=%v vol=%d", err, poor.Balance.StockVol)
+	}
+}
@@ -0,0 +1,51 @@
+package common
+
+import (
+	"math"
+)
+
+// FeeModel 交易费用计算方式, code为股票代码 (可带市场前缀, 如 1.510500)
+type FeeModel interface {
+	CountFee(code string, mode OpMode, prize float64, vol int) (fee float64)
+}
+
+// StandardFee A股常规交易费用: 双向收取佣金(有最低收费), 卖出收取印花税, 沪市股票双向收取过户费, ETF免收印花税和过户费
+type StandardFee struct {
+	CommissionRate float64 `json:"commissionRate"` // 佣金费率, 例如万2.5为0.00025
+	MinCommission  float64 `json:"minCommission"`  // 单笔最低佣金 (元)
+	StampDutyRate  float64 `json:"stampDutyRate"`  // 印花税率, 仅卖出收取
+	TransferRate   float64 `json:"transferRate"`   // 过户费率, 仅沪市股票收取
+}
+
+// NewStandardFee 按照常见券商费率创建费用模型: 佣金万2.5最低5元, 印花税千分之一, 过户费十万分之一
+func NewStandardFee() *StandardFee {
+	return &StandardFee{
+		CommissionRate: 0.00025,
+		MinCommission:  5,
+		StampDutyRate:  0.001,
+		TransferRate:   0.00001,
+	}
+}
+
+// CountFee 计算单笔交易的总费用, 结果保留两位小数
+func (s *StandardFee) CountFee(code string, mode OpMode, prize float64, vol int) (fee float64) {
+	value := prize * float64(vol)
+	if value <= 0 {
+		return 0
+	}
+	fee = math.Max(value*s.CommissionRate, s.MinCommission)
+	if IsETF(code) {
+		return roundFee(fee)
+	}
+	if mode == ModeShell {
+		fee += value * s.StampDutyRate
+	}
+	if IsSHCode(code) {
+		fee += value * s.TransferRate
+	}
+	return roundFee(fee)
+}
+
+func roundFee(fee float64) float64 {
+	return math.Round(fee*100) / 100
+}
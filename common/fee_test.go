@@ -0,0 +1,24 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestStandardFee(t *testing.T) {
+	fee := NewStandardFee()
+	for _, c := range []struct {
+		code string
+		mode OpMode
+		want float64
+	}{
+		{"1.600036", ModeShell, 126}, // 佣金25 + 印花税100 + 过户费1
+		{"1.600036", ModeBuy, 26},
+		{"0.000001", ModeShell, 125},
+		{"1.510500", ModeShell, 25}, // ETF不收印花税和过户费
+		{"1.513050", ModeBuy, 25},
+	} {
+		if got := fee.CountFee(c.code, c.mode, 10, 10000); got != c.want {
+			t.Fatalf("unexpect fee: code=%s mode=%s got=%.2f want=%.2f", c.code, c.mode, got, c.want)
+		}
+	}
+}
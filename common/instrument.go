@@ -0,0 +1,123 @@
+package common
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// IsETF 根据代码判断是否为场内基金 (沪市5开头, 深市15/16开头)
+func IsETF(code string) bool {
+	code = trimMarket(code)
+	return strings.HasPrefix(code, "5") || strings.HasPrefix(code, "15") || strings.HasPrefix(code, "16")
+}
+
+// IsSHCode 根据代码判断是否为沪市证券 (5, 6, 9开头)
+func IsSHCode(code string) bool {
+	code = trimMarket(code)
+	return strings.HasPrefix(code, "5") || strings.HasPrefix(code, "6") || strings.HasPrefix(code, "9")
+}
+
+// 去掉secID中的市场前缀, 例如 1.510500 -> 510500
+func trimMarket(code string) string {
+	if idx := strings.Index(code, "."); idx >= 0 {
+		return code[idx+1:]
+	}
+	return code
+}
+
+// IsT0Code 根据代码判断是否为支持T+0交易的品种 (沪市债券ETF 511, 跨境ETF 513, 黄金ETF 518)
+// 深市同类品种代码没有统一前缀, 需要手动设置Account.T0
+func IsT0Code(code string) bool {
+	code = trimMarket(code)
+	return strings.HasPrefix(code, "511") || strings.HasPrefix(code, "513") || strings.HasPrefix(code, "518")
+}
+
+// InstrumentRule 品种的委托规则
+type InstrumentRule struct {
+	LotSize   int     `json:"lotSize"`   // 每手份额, 买入数量必须为整手, 卖出时不足一手的零股需要一次性卖出
+	Tick      float64 `json:"tick"`      // 最小价格变动单位
+	LimitRate float64 `json:"limitRate"` // 涨跌幅限制比例, 例如10%为0.1 (0=不限制)
+}
+
+// GetInstrumentRule 根据代码和名称获取默认的委托规则: 每手100份, 股票价格精度0.01元, 场内基金0.001元
+// 涨跌幅限制按代码和名称判断 (见LimitRate); 代码为空时不限制价格精度和涨跌幅
+func GetInstrumentRule(code string, name string) InstrumentRule {
+	if code == "" {
+		return InstrumentRule{LotSize: 100}
+	}
+	rule := InstrumentRule{LotSize: 100, Tick: 0.01, LimitRate: LimitRate(code, name)}
+	if IsETF(code) {
+		rule.Tick = 0.001
+	}
+	return rule
+}
+
+// CheckVol 检查委托数量是否合法, holdVol为当前持有份额 (仅卖出时使用)
+func (r InstrumentRule) CheckVol(mode OpMode, vol int, holdVol int) (isOk bool, reason string) {
+	if r.LotSize <= 1 || vol%r.LotSize == 0 {
+		return true, ""
+	}
+	if mode == ModeShell && vol%r.LotSize == holdVol%r.LotSize {
+		return true, "" // 零股部分一次性卖出
+	}
+	if mode == ModeShell {
+		return false, fmt.Sprintf("卖出数量需为%d的整数倍 (零股需一次性卖出): 卖出=%d 持有=%d", r.LotSize, vol, holdVol)
+	}
+	return false, fmt.Sprintf("买入数量需为%d的整数倍: 买入=%d", r.LotSize, vol)
+}
+
+// CheckPrice 检查价格是否为最小变动单位的整数倍
+func (r InstrumentRule) CheckPrice(prize float64) (isOk bool, reason string) {
+	if r.Tick <= 0 || math.Abs(r.SnapPrice(prize)-prize) < r.Tick*1e-3 {
+		return true, ""
+	}
+	return false, fmt.Sprintf("价格需为%g的整数倍: 价格=%v", r.Tick, prize)
+}
+
+// SnapPrice 将价格调整为最接近的合法价格
+func (r InstrumentRule) SnapPrice(prize float64) float64 {
+	if r.Tick <= 0 {
+		return prize
+	}
+	snapped := math.Round(prize/r.Tick) * r.Tick
+	return math.Round(snapped*1e6) / 1e6 // 消除浮点误差
+}
+
+// LegalVol 获取最接近vol的合法委托数量, 卖出时不超过持有份额, 持有份额不足vol时返回全部持有份额
+func (r InstrumentRule) LegalVol(mode OpMode, vol int, holdVol int) int {
+	if vol <= 0 {
+		return 0
+	}
+	if mode == ModeShell && vol >= holdVol {
+		return holdVol
+	}
+	if r.LotSize <= 1 {
+		return vol
+	}
+	legal := (vol + r.LotSize/2) / r.LotSize * r.LotSize
+	if mode == ModeShell && legal > holdVol {
+		legal -= r.LotSize
+	}
+	return legal
+}
+
+// LimitRate 根据代码和名称获取涨跌幅限制比例: 科创板/创业板20%, ST股票5%, 其他10%
+func LimitRate(code string, name string) float64 {
+	code = trimMarket(code)
+	if strings.HasPrefix(code, "688") || strings.HasPrefix(code, "300") || strings.HasPrefix(code, "301") {
+		return 0.2
+	}
+	if strings.Contains(strings.ToUpper(name), "ST") {
+		return 0.05
+	}
+	return 0.1
+}
+
+// LimitPrice 根据昨收价计算涨停价和跌停价 (按价格精度四舍五入), 没有涨跌幅限制时返回0
+func (r InstrumentRule) LimitPrice(prevClose float64) (up float64, down float64) {
+	if r.LimitRate <= 0 || prevClose <= 0 {
+		return 0, 0
+	}
+	return r.SnapPrice(prevClose * (1 + r.LimitRate)), r.SnapPrice(prevClose * (1 - r.LimitRate))
+}
@@ -0,0 +1,114 @@
+package common
+
+import (
+	"errors"
+	"github.com/BlackCarDriver/StockMaster/calendar"
+	"testing"
+)
+
+func TestInstrumentRule(t *testing.T) {
+	moment := mockNode("2022-06-01", 10)
+	ts := moment.Timestamp
+	account := mockAccount("Rule", 100000)
+	account.T0, account.Balance.StockVol = true, 130
+	if err := account.Trad(ModeBuy, 10, 37, moment); !errors.Is(err, ErrLotSize) {
+		t.Fatalf("expect odd lot buy rejected: err=%v", err)
+	}
+	if err := account.Trad(ModeBuy, 10.005, 100, moment); !errors.Is(err, ErrPriceTick) {
+		t.Fatalf("expect off tick price rejected: err=%v", err)
+	}
+	if err := account.Trad(ModeShell, 10, 20, moment); !errors.Is(err, ErrLotSize) {
+		t.Fatalf("expect partial odd lot sell rejected: err=%v", err)
+	}
+	if err := account.Trad(ModeShell, 10, 130, moment); err != nil {
+		t.Fatalf("expect whole remainder sell ok: err=%v", err)
+	}
+	if got := account.LegalVol(ModeBuy, 370); got != 400 {
+		t.Fatalf("unexpect legal vol: %d", got)
+	}
+	if got := account.LegalPrice(10.126); got != 10.13 {
+		t.Fatalf("unexpect legal price: %v", got)
+	}
+
+	account.TargetStock = "1.510500"
+	account.CreateEntrust(ModeBuy, 5.12345, 200, ts, 0)
+	if len(account.BuyEntrust) != 1 || account.BuyEntrust[0].Price != 5.123 {
+		t.Fatalf("expect entrust price snapped to etf tick: %+v", account.BuyEntrust)
+	}
+	if _, err := account.CreateEntrust(ModeBuy, 5.1, 150, ts, 0); err == nil || len(account.BuyEntrust) != 1 {
+		t.Fatalf("expect odd lot entrust rejected")
+	}
+	if rule := GetInstrumentRule("", ""); rule.Tick != 0 || rule.LimitRate != 0 {
+		t.Fatalf("expect no tick and limit without code: %+v", rule)
+	}
+}
+
+func TestPriceLimit(t *testing.T) {
+	var nodes []KLineNode
+	for i, desc := range []string{"2022-06-01", "2022-06-02", "2022-06-06"} {
+		nodes = append(nodes, mockNode(desc, 10))
+		if i > 0 { // 一字涨停后继续上涨
+			nodes[i] = KLineNode{Timestamp: nodes[i].Timestamp, TimeDesc: desc, Start: 11, End: 11, Top: 11, Bottom: 11, PriceRise: 1}
+		}
+		if i == 2 {
+			nodes[i].Bottom = 10.6
+		}
+	}
+	account := mockAccount("Limit", 100000)
+	account.Balance.StockVol = 1000
+	account.UpdateStat(nodes[0])
+	if account.Limit.Up != 11 || account.Limit.Down != 9 {
+		t.Fatalf("unexpect limit: %+v", account.Limit)
+	}
+	if err := account.Trad(ModeShell, 11.5, 100, nodes[0]); !errors.Is(err, ErrPriceLimit) {
+		t.Fatalf("expect trade above limit up rejected: err=%v", err)
+	}
+	// 当日失效的委托单超出涨跌停范围时拒绝, 跨交易日的委托单在成交时检查
+	dayClose := calendar.DayClose(calendar.InLocation(nodes[0].Timestamp)).Unix()
+	if _, err := account.CreateEntrust(ModeShell, 11.5, 100, nodes[0].Timestamp, dayClose); !errors.Is(err, ErrPriceLimit) {
+		t.Fatalf("expect entrust above limit up rejected: err=%v", err)
+	}
+	if n := len(account.ActionLog); account.ActionLog[n-1].Mode != ActionLimit {
+		t.Fatalf("expect limit rejection logged: %+v", account.ActionLog[n-1])
+	}
+	if _, err := account.CreateEntrust(ModeShell, 11.5, 100, nodes[0].Timestamp, 0); err != nil {
+		t.Fatalf("expect long term entrust accepted: err=%v", err)
+	}
+	if _, err := account.CreateEntrust(ModeShell, 10.8, 100, nodes[0].Timestamp, 0); err != nil {
+		t.Fatalf("expect entrust in band accepted: err=%v", err)
+	}
+	expiring, _ := account.CreateEntrust(ModeShell, 10.9, 100, nodes[0].Timestamp, dayClose)
+
+	account.UpdateStat(nodes[1])
+	if mode, _ := account.ExecuteEntrust(nodes[1]); mode != ModeWait {
+		t.Fatalf("expect no fill on one price limit bar")
+	}
+	if n := len(account.ActionLog); account.ActionLog[n-1].Mode != ActionLimit {
+		t.Fatalf("expect limit action log: %+v", account.ActionLog[n-1])
+	}
+	if record, _ := account.GetEntrust(expiring); record.Status != StatusExpired {
+		t.Fatalf("expect entrust expired on locked bar: %+v", record)
+	}
+
+	account.UpdateStat(nodes[2])
+	if account.Limit.Up != 12.1 || account.Limit.Down != 9.9 {
+		t.Fatalf("expect limit from previous close: %+v", account.Limit)
+	}
+	if mode, _ := account.ExecuteEntrust(nodes[2]); mode != ModeShell {
+		t.Fatalf("expect fill on normal bar")
+	}
+	if rate := LimitRate("0.300750", ""); rate != 0.2 {
+		t.Fatalf("unexpect ChiNext limit rate: %v", rate)
+	}
+	if rate := LimitRate("1.600000", "*ST浦发"); rate != 0.05 {
+		t.Fatalf("unexpect ST limit rate: %v", rate)
+	}
+
+	// ST股票按名称判断, 涨跌幅限制为5%
+	st := mockAccount("ST", 100000)
+	st.TargetStock, st.TargetName = "600000", "*ST浦发"
+	st.UpdateStat(nodes[0])
+	if st.Limit.Up != 10.5 || st.Limit.Down != 9.5 {
+		t.Fatalf("unexpect ST limit: %+v", st.Limit)
+	}
+}
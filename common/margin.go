@@ -0,0 +1,210 @@
+package common
+
+import (
+	"fmt"
+	"math"
+)
+
+// MarginConfig 融资融券配置, 账号设置后可以在现金不足时融资买入, 以及融券卖出
+type MarginConfig struct {
+	CollateralRatio  float64 `json:"collateralRatio"`  // 保证金比例, 融资负债和融券市值之和不能超过 净资产/保证金比例 (0按1处理)
+	FinanceRate      float64 `json:"financeRate"`      // 融资年利率, 例如0.08
+	BorrowRate       float64 `json:"borrowRate"`       // 融券年费率, 按融券市值计算
+	MaintenanceRatio float64 `json:"maintenanceRatio"` // 维持担保比例下限, 低于该值时强制平仓, 例如1.3 (0=不监控)
+	DaysPerYear      int     `json:"daysPerYear"`      // 年化利率的计息天数 (0按360处理)
+}
+
+// MarginDebt 融资融券负债
+type MarginDebt struct {
+	FinanceDebt   float64 `json:"financeDebt"`   // 融资负债, 卖出时优先偿还
+	ShortVol      int     `json:"shortVol"`      // 融券卖出尚未归还的份额
+	ShortProceeds float64 `json:"shortProceeds"` // 融券卖出所得 (扣除费用), 冻结到买券还券时释放
+	InterestDay   string  `json:"interestDay"`   // 上次计息的交易日, 格式: 2006-01-02
+}
+
+// ShortSell 融券卖出, 借入份额按委托价卖出, 卖出所得冻结到买券还券时释放
+func (a *Account) ShortSell(prize float64, vol int, moment KLineNode) (err error) {
+	if a.Margin == nil {
+		err = newTradError(ErrInvalidParams, "非融资融券账户,无法融券卖出")
+		a.recordAction(moment.Timestamp, ActionGiveUp, err.Error())
+		return
+	}
+	entrustPrize, entrustVol := prize, vol
+	if prize, vol, err = a.checkDeal(ModeShell, prize, vol, 0, moment); err != nil {
+		return
+	}
+	value := prize * float64(vol)
+	fee := a.CountFee(ModeShell, prize, vol)
+	if !a.canFinance(value, prize) {
+		err = newTradError(ErrInsufficientCash, "保证金不足,无法融券卖出: 委托价=%.2f 请求卖出=%d 净资产=%.2f 负债=%.2f",
+			prize, vol, a.netAsset(prize), a.exposure(prize))
+		a.recordAction(moment.Timestamp, ActionGiveUp, err.Error())
+		return
+	}
+	a.commitFill(moment, vol)
+	a.Debt.ShortVol += vol
+	a.Debt.ShortProceeds += value - fee
+	a.TradStat.TotalFee += fee
+	a.recordAction(moment.Timestamp, ActionShortSell, fmt.Sprintf("融券卖出, 成交份额=%d 成交价=%.2f 成交金额=%.2f 手续费=%.2f 融券份额=%d",
+		vol, prize, value, fee, a.Debt.ShortVol))
+	a.recordTradLog(moment.Timestamp, ModeShell, prize, vol, fee, entrustPrize, entrustVol, 0)
+	a.TradLog[len(a.TradLog)-1].Short = true
+	a.LastDeal = &moment
+	a.maintainTradStat(ModeShell)
+	return
+}
+
+// CoverShort 买券还券, 按委托价买入份额归还融券, 释放对应的融券卖出所得
+func (a *Account) CoverShort(prize float64, vol int, moment KLineNode) (err error) {
+	if a.Margin == nil || vol > a.Debt.ShortVol {
+		err = newTradError(ErrInvalidParams, "融券份额不足,无法买券还券: 请求买入=%d 融券份额=%d", vol, a.Debt.ShortVol)
+		a.recordAction(moment.Timestamp, ActionGiveUp, err.Error())
+		return
+	}
+	entrustPrize, entrustVol := prize, vol
+	if prize, vol, err = a.checkDeal(ModeBuy, prize, vol, a.Debt.ShortVol, moment); err != nil {
+		return
+	}
+	value := prize * float64(vol)
+	fee := a.CountFee(ModeBuy, prize, vol)
+	release := a.Debt.ShortProceeds * float64(vol) / float64(a.Debt.ShortVol)
+	pnl := release - value - fee
+	a.commitFill(moment, vol)
+	a.Debt.ShortVol -= vol
+	a.Debt.ShortProceeds -= release
+	if a.Debt.ShortVol == 0 {
+		a.Debt.ShortProceeds = 0 // 全部归还时消除累计的计算误差
+	}
+	a.addCash(pnl)
+	a.TradStat.TotalFee += fee
+	a.TradStat.RealizedPnL += pnl
+	a.recordAction(moment.Timestamp, ActionCover, fmt.Sprintf("买券还券, 成交份额=%d 成交价=%.2f 成交金额=%.2f 手续费=%.2f 实现盈亏=%.2f 融券份额=%d",
+		vol, prize, value, fee, pnl, a.Debt.ShortVol))
+	a.recordTradLog(moment.Timestamp, ModeBuy, prize, vol, fee, entrustPrize, entrustVol, pnl)
+	a.TradLog[len(a.TradLog)-1].Short = true
+	a.financeShortage(moment.Timestamp)
+	a.LastDeal = &moment
+	a.maintainTradStat(ModeBuy)
+	return
+}
+
+// NetAsset 账户净资产: 现金+持仓市值+融券冻结资金-融资负债-融券市值, 按最新价格计算
+func (a *Account) NetAsset() float64 {
+	if a.LastPrize == nil {
+		return a.Cash()
+	}
+	return a.netAsset(a.LastPrize.End)
+}
+
+// MaintenanceRatio 维持担保比例: (现金+持仓市值+融券冻结资金)/(融资负债+融券市值), 没有负债时返回0
+func (a *Account) MaintenanceRatio(prize float64) float64 {
+	debt := a.exposure(prize)
+	if debt <= 0 {
+		return 0
+	}
+	return (a.netAsset(prize) + debt) / debt
+}
+
+func (a *Account) netAsset(prize float64) float64 {
+	return prize*float64(a.Balance.StockVol) + a.Cash() + a.Debt.ShortProceeds - a.exposure(prize)
+}
+
+// 融资负债与融券市值之和
+func (a *Account) exposure(prize float64) float64 {
+	return a.Debt.FinanceDebt + prize*float64(a.Debt.ShortVol)
+}
+
+// 判断保证金是否足够再融资或融券amount金额
+func (a *Account) canFinance(amount float64, prize float64) bool {
+	if a.Margin == nil {
+		return false
+	}
+	ratio := a.Margin.CollateralRatio
+	if ratio <= 0 {
+		ratio = 1
+	}
+	return (a.exposure(prize)+amount)*ratio <= a.netAsset(prize)+1e-9
+}
+
+// 现金不足的部分记为融资负债
+func (a *Account) financeShortage(timestamp int64) {
+	if a.Margin == nil || a.Cash() >= 0 {
+		return
+	}
+	amount := -a.Cash()
+	a.addCash(amount)
+	a.Debt.FinanceDebt += amount
+	a.recordAction(timestamp, ActionFinance, fmt.Sprintf("融资借款=%.2f 融资负债=%.2f", amount, a.Debt.FinanceDebt))
+}
+
+// 使用现金偿还融资负债
+func (a *Account) repayFinance(timestamp int64) {
+	amount := math.Min(a.Cash(), a.Debt.FinanceDebt)
+	if a.Margin == nil || amount <= 0 {
+		return
+	}
+	a.addCash(-amount)
+	a.Debt.FinanceDebt -= amount
+	if a.Debt.FinanceDebt < 1e-6 {
+		a.Debt.FinanceDebt = 0
+	}
+	a.recordAction(timestamp, ActionRepay, fmt.Sprintf("偿还融资=%.2f 融资负债=%.2f", amount, a.Debt.FinanceDebt))
+}
+
+// 进入新的交易日时按自然日计算融资利息和融券费用, 从现金扣除, 现金不足时计入融资负债
+// 需要在UpdateStat更新最新价格之前调用, 融券费用按上一个节点的收盘价计算
+func (a *Account) accrueInterest(moment KLineNode) {
+	today := tradeDay(moment.Timestamp)
+	if a.Margin == nil || today <= a.Debt.InterestDay {
+		return
+	}
+	lastDay := a.Debt.InterestDay
+	a.Debt.InterestDay = today
+	if lastDay == "" || a.LastPrize == nil || (a.Debt.FinanceDebt == 0 && a.Debt.ShortVol == 0) {
+		return
+	}
+	days := naturalDays(lastDay, today)
+	if days <= 0 {
+		return
+	}
+	daysPerYear := float64(a.Margin.DaysPerYear)
+	if daysPerYear <= 0 {
+		daysPerYear = 360
+	}
+	financeFee := a.Debt.FinanceDebt * a.Margin.FinanceRate * days / daysPerYear
+	borrowFee := a.LastPrize.End * float64(a.Debt.ShortVol) * a.Margin.BorrowRate * days / daysPerYear
+	if financeFee+borrowFee <= 0 {
+		return
+	}
+	a.addCash(-(financeFee + borrowFee))
+	a.TradStat.MarginInterest += financeFee + borrowFee
+	a.recordAction(moment.Timestamp, ActionInterest, fmt.Sprintf("计息天数=%.0f 融资利息=%.2f 融券费用=%.2f 融资负债=%.2f 融券份额=%d",
+		days, financeFee, borrowFee, a.Debt.FinanceDebt, a.Debt.ShortVol))
+	a.financeShortage(moment.Timestamp)
+}
+
+// 按节点开盘价检查维持担保比例, 低于下限时撤销全部委托, 按开盘价卖出全部可卖持仓并买券归还全部融券
+// 强制平仓不受买卖锁限制, 涨跌停或T+1等原因无法成交的部分在之后的节点继续检查
+func (a *Account) checkMaintenance(moment KLineNode) {
+	if a.Margin == nil || a.Margin.MaintenanceRatio <= 0 {
+		return
+	}
+	prize := a.LegalPrice(moment.Start)
+	ratio := a.MaintenanceRatio(prize)
+	if ratio <= 0 || ratio >= a.Margin.MaintenanceRatio {
+		return
+	}
+	a.recordAction(moment.Timestamp, ActionMarginCall, fmt.Sprintf("维持担保比例不足,强制平仓: 担保比例=%.3f 下限=%.3f 价格=%.2f 融资负债=%.2f 融券份额=%d",
+		ratio, a.Margin.MaintenanceRatio, prize, a.Debt.FinanceDebt, a.Debt.ShortVol))
+	a.CancelAll(ModeBuy, moment.Timestamp)
+	a.CancelAll(ModeShell, moment.Timestamp)
+	setting := a.Setting
+	a.Setting = Setting{}
+	if vol := a.SellableVol(moment.Timestamp); vol > 0 && a.Debt.FinanceDebt > 0 {
+		a.deal(ModeShell, prize, vol, moment)
+	}
+	if a.Debt.ShortVol > 0 {
+		a.CoverShort(prize, a.Debt.ShortVol, moment)
+	}
+	a.Setting = setting
+}
@@ -0,0 +1,65 @@
+package common
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestMarginAccount(t *testing.T) {
+	nodes := []KLineNode{mockNode("2022-06-01", 10), mockNode("2022-06-02", 11)}
+	for i := range nodes {
+		nodes[i].Top, nodes[i].Bottom = nodes[i].End, nodes[i].End-0.2
+	}
+	newAccount := func(margin *MarginConfig) *Account {
+		account := mockAccount("Margin", 10000)
+		account.T0, account.Margin = true, margin
+		account.UpdateStat(nodes[0])
+		return account
+	}
+
+	// 现金账户不能融资融券
+	account := newAccount(nil)
+	if err := account.Trad(ModeBuy, 10, 1500, nodes[0]); !errors.Is(err, ErrInsufficientCash) {
+		t.Fatalf("expect cash account refuse financing: err=%v", err)
+	}
+	if err := account.ShortSell(10, 100, nodes[0]); !errors.Is(err, ErrInvalidParams) {
+		t.Fatalf("expect cash account refuse short sell: err=%v", err)
+	}
+
+	// 融资买入, 超出保证金时拒绝, 按日计息, 卖出时优先还款
+	account = newAccount(&MarginConfig{CollateralRatio: 1, FinanceRate: 0.036, DaysPerYear: 360})
+	if err := account.Trad(ModeBuy, 10, 1500, nodes[0]); err != nil || account.Debt.FinanceDebt != 5000 || account.Cash() != 0 {
+		t.Fatalf("expect financing buy: err=%v debt=%+v cash=%.2f", err, account.Debt, account.Cash())
+	}
+	if err := account.Trad(ModeBuy, 10, 1000, nodes[0]); !errors.Is(err, ErrInsufficientCash) {
+		t.Fatalf("expect collateral limit: err=%v", err)
+	}
+	account.UpdateStat(nodes[1])
+	if math.Abs(account.TradStat.MarginInterest-0.5) > 1e-9 || math.Abs(account.Debt.FinanceDebt-5000.5) > 1e-9 {
+		t.Fatalf("unexpect interest: stat=%+v debt=%+v", account.TradStat, account.Debt)
+	}
+	if err := account.Trad(ModeShell, 11, 500, nodes[1]); err != nil || account.Debt.FinanceDebt != 0 || math.Abs(account.Cash()-499.5) > 1e-9 {
+		t.Fatalf("expect sell repay debt: err=%v debt=%+v cash=%.2f", err, account.Debt, account.Cash())
+	}
+
+	// 融券卖出后价格上涨, 维持担保比例不足时强制买券还券
+	account = newAccount(&MarginConfig{CollateralRatio: 1, MaintenanceRatio: 1.9})
+	if err := account.ShortSell(10, 1000, nodes[0]); err != nil || account.Debt.ShortVol != 1000 || account.Cash() != 10000 {
+		t.Fatalf("expect short sell: err=%v debt=%+v", err, account.Debt)
+	}
+	if ratio := account.MaintenanceRatio(10); ratio != 2 {
+		t.Fatalf("unexpect maintenance ratio: %.3f", ratio)
+	}
+	account.UpdateStat(nodes[1])
+	if account.Debt.ShortVol != 0 || account.Cash() != 9000 || account.TradStat.RealizedPnL != -1000 || !account.TradLog[1].Short {
+		t.Fatalf("expect forced liquidation: debt=%+v cash=%.2f log=%+v", account.Debt, account.Cash(), account.TradLog)
+	}
+	called := false
+	for _, action := range account.ActionLog {
+		called = called || action.Mode == ActionMarginCall
+	}
+	if !called {
+		t.Fatalf("expect margin call logged: %+v", account.ActionLog)
+	}
+}
@@ -0,0 +1,157 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Portfolio 组合账号, 每个品种使用一个子账号记录持仓, 委托单和交易规则, 所有子账号共享组合的现金
+// 子账号通过AddAccount加入组合后, 买卖和分红直接增减组合的现金, 子账号的Balance.BalanceRMB不再使用
+type Portfolio struct {
+	Name        string              `json:"name"`
+	Note        string              `json:"note"`        // 备注
+	InitFundRMB float64             `json:"InitFundRMB"` // 初始总资产
+	BalanceRMB  float64             `json:"balanceRmb"`  // 共享的可用现金
+	TradStat    TradInfo            `json:"TradInfo"`    // 组合的交易统计, 总资产按各品种各自的最新价格计算
+	Accounts    map[string]*Account `json:"accounts"`    // 各品种的子账号, key为子账号的TargetStock
+	YieldDay    string              `json:"yieldDay"`    // 上次计算闲置现金收益的交易日
+	Sampling    EquitySampling      `json:"sampling"`    // 资产曲线的采样频率 (默认不记录)
+	Equity      []EquityPoint       `json:"equity"`      // 资产曲线, 模拟时在每个节点执行策略之后按采样频率记录
+	ActionLog   []Action            `json:"actionLog"`   // 组合的操作日志, 例如共享现金计息 (交易和委托记录在各子账号中)
+	CashYield   CashYield           `json:"-"`           // 共享现金的收益率 (nil=现金没有收益), 子账号的CashYield不生效
+}
+
+// NewPortfolio 创建组合账号, 初始资金全部为可用现金
+func NewPortfolio(name string, initFund float64) *Portfolio {
+	return &Portfolio{
+		Name:        name,
+		InitFundRMB: initFund,
+		BalanceRMB:  initFund,
+		Accounts:    make(map[string]*Account),
+	}
+}
+
+// AddAccount 把子账号加入组合, 子账号原有的现金并入组合的现金
+// 融资融券需要按账户整体计算担保比例, 子账号不支持融资融券
+func (p *Portfolio) AddAccount(account *Account) (err error) {
+	if account == nil || account.TargetStock == "" {
+		return fmt.Errorf("unexpect params: account require TargetStock")
+	}
+	if account.Margin != nil {
+		return fmt.Errorf("unexpect params: margin account is not supported by portfolio, code=%s", account.TargetStock)
+	}
+	if _, isExist := p.Accounts[account.TargetStock]; isExist {
+		return fmt.Errorf("unexpect params: duplicate account, code=%s", account.TargetStock)
+	}
+	if p.Accounts == nil {
+		p.Accounts = make(map[string]*Account)
+	}
+	if account.Name == "" {
+		account.Name = fmt.Sprintf("%s_%s", p.Name, account.TargetStock)
+	}
+	p.BalanceRMB += account.Balance.BalanceRMB
+	account.Balance.BalanceRMB = 0
+	account.portfolio = p
+	p.Accounts[account.TargetStock] = account
+	return
+}
+
+// Account 获取品种对应的子账号
+func (p *Portfolio) Account(symbol string) (account *Account, isExist bool) {
+	account, isExist = p.Accounts[symbol]
+	return
+}
+
+// Symbols 获取组合中的所有品种代码, 按代码升序排列
+func (p *Portfolio) Symbols() (symbols []string) {
+	for symbol := range p.Accounts {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return
+}
+
+// UpdateStat 更新品种的最新价格和组合的统计信息, 进入新的交易日时计算共享现金的收益
+func (p *Portfolio) UpdateStat(symbol string, moment KLineNode) (err error) {
+	account, isExist := p.Accounts[symbol]
+	if !isExist {
+		return fmt.Errorf("unexpect symbol: symbol=%s", symbol)
+	}
+	p.accrueCashYield(moment)
+	account.UpdateStat(moment)
+	return
+}
+
+// MarketValue 各品种持仓按各自最新价格计算的总市值
+func (p *Portfolio) MarketValue() (value float64) {
+	for _, symbol := range p.Symbols() {
+		if account := p.Accounts[symbol]; account.LastPrize != nil {
+			value += account.LastPrize.End * float64(account.Balance.StockVol)
+		}
+	}
+	return
+}
+
+// TotalValue 组合总资产, 即现金与持仓总市值之和
+func (p *Portfolio) TotalValue() float64 {
+	return p.BalanceRMB + p.MarketValue()
+}
+
+// 汇总各子账号的统计数据, 并按组合的持仓和总资产更新最高最低值
+func (p *Portfolio) maintainTradStat() {
+	stat, vol, cost := p.TradStat, 0, 0.0
+	isFirst := stat.BuyCounter+stat.SellCounter == 0
+	stat.BuyCounter, stat.SellCounter = 0, 0
+	stat.AdjustGain, stat.DividendRMB, stat.BonusVol, stat.TotalFee, stat.RealizedPnL, stat.MarginInterest = 0, 0, 0, 0, 0, 0
+	for _, symbol := range p.Symbols() { // 按固定顺序累加, 保证结果可以复现
+		account := p.Accounts[symbol]
+		t := account.TradStat
+		stat.BuyCounter += t.BuyCounter
+		stat.SellCounter += t.SellCounter
+		stat.AdjustGain += t.AdjustGain
+		stat.DividendRMB += t.DividendRMB
+		stat.BonusVol += t.BonusVol
+		stat.TotalFee += t.TotalFee
+		stat.RealizedPnL += t.RealizedPnL
+		stat.MarginInterest += t.MarginInterest
+		vol += account.Balance.StockVol
+		cost += account.Balance.CostRMB
+	}
+	if stat.BuyCounter+stat.SellCounter == 0 { // 首次交易之前不统计
+		return
+	}
+
+	total := p.TotalValue()
+	if isFirst {
+		stat.MinVol, stat.MinCost, stat.MinValue = vol, cost, total
+	}
+	if vol > stat.MaxVol {
+		stat.MaxVol = vol
+	}
+	if vol < stat.MinVol {
+		stat.MinVol = vol
+	}
+	if cost > stat.MaxCost {
+		stat.MaxCost = cost
+	}
+	if cost < stat.MinCost {
+		stat.MinCost = cost
+	}
+	if total > stat.MaxValue {
+		stat.MaxValue = total
+	}
+	if total < stat.MinValue {
+		stat.MinValue = total
+	}
+	p.TradStat = stat
+}
+
+// 记录组合的操作记录
+func (p *Portfolio) recordAction(timestamp int64, actionType ActionType, desc string) {
+	action := Action{
+		Mode:      actionType,
+		Timestamp: timestamp,
+		Desc:      desc,
+	}
+	p.ActionLog = append(p.ActionLog, action)
+}
@@ -0,0 +1,39 @@
+package common
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPortfolio(t *testing.T) {
+	// 两个品种共享现金, 一个品种买入后另一个品种余额不足
+	nodes := []KLineNode{mockNode("2022-06-01", 10), mockNode("2022-06-02", 10.5)}
+	portfolio := NewPortfolio("Shared", 10000)
+	for _, code := range []string{"510500", "513050"} {
+		if err := portfolio.AddAccount(&Account{TargetStock: code, T0: true}); err != nil {
+			t.Fatalf("add account fail: err=%v", err)
+		}
+	}
+	if err := portfolio.AddAccount(&Account{TargetStock: "510500"}); err == nil {
+		t.Fatalf("expect error on duplicate account")
+	}
+	first, _ := portfolio.Account("510500")
+	second, _ := portfolio.Account("513050")
+	portfolio.UpdateStat("510500", nodes[0])
+	portfolio.UpdateStat("513050", nodes[0])
+	if err := first.Trad(ModeBuy, 10, 600, nodes[0]); err != nil || portfolio.BalanceRMB != 4000 || first.Balance.BalanceRMB != 0 {
+		t.Fatalf("expect buy use shared cash: err=%v cash=%.2f", err, portfolio.BalanceRMB)
+	}
+	if err := second.Trad(ModeBuy, 10, 600, nodes[0]); !errors.Is(err, ErrInsufficientCash) {
+		t.Fatalf("expect insufficient shared cash: err=%v", err)
+	}
+	if err := first.Trad(ModeShell, 11, 600, nodes[1]); err != nil || portfolio.BalanceRMB != 10600 {
+		t.Fatalf("expect sell return shared cash: err=%v cash=%.2f", err, portfolio.BalanceRMB)
+	}
+	if s := portfolio.TradStat; s.BuyCounter != 1 || s.SellCounter != 1 || s.RealizedPnL != 600 || s.MaxCost != 6000 {
+		t.Fatalf("unexpect portfolio stat: %+v", s)
+	}
+	if err := portfolio.AddAccount(&Account{TargetStock: "600000", Margin: &MarginConfig{}}); err == nil {
+		t.Fatalf("expect margin sub-account rejected")
+	}
+}
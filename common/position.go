@@ -0,0 +1,179 @@
+package common
+
+import (
+	"math"
+	"sort"
+)
+
+// CostMethod 卖出时计算持仓成本的方式
+type CostMethod string
+
+const (
+	CostFIFO     CostMethod = ""         // 先进先出: 优先卖出最早买入的批次
+	CostAverage  CostMethod = "average"  // 移动平均: 所有批次按平均成本计算
+	CostSpecific CostMethod = "specific" // 指定批次: 通过TradLot指定卖出的批次, 未指定时优先卖出单位成本最高的可卖批次
+)
+
+// TradLot 卖出指定持仓批次的份额, 卖出成本按该批次计算, 其他规则与Trad相同
+func (a *Account) TradLot(lotID int, prize float64, vol int, moment KLineNode) (err error) {
+	a.syncLots()
+	for _, lot := range a.Lots {
+		if lot.ID != lotID {
+			continue
+		}
+		if vol > lot.Vol {
+			err = newTradError(ErrInsufficientShares, "批次份额不足,无法卖出: 批次=%d 请求卖出=%d 批次份额=%d", lotID, vol, lot.Vol)
+		} else if !a.T0 && lot.TradeDay == tradeDay(moment.Timestamp) {
+			err = newTradError(ErrSettlement, "T+1限制,当日买入的批次不能卖出: 批次=%d 买入时间=%s", lotID, TimeFormat(lot.Timestamp))
+		}
+		if err != nil {
+			a.recordAction(moment.Timestamp, ActionGiveUp, err.Error())
+			return
+		}
+		_, err = a.dealLot(ModeShell, prize, vol, lotID, moment)
+		return
+	}
+	err = newTradError(ErrInvalidParams, "持仓批次不存在: 批次=%d", lotID)
+	a.recordAction(moment.Timestamp, ActionGiveUp, err.Error())
+	return
+}
+
+// UnrealizedPnL 按最新价格计算的持仓浮动盈亏
+func (a *Account) UnrealizedPnL() float64 {
+	if a.LastPrize == nil {
+		return 0
+	}
+	return a.LastPrize.End*float64(a.Balance.StockVol) - a.Balance.CostRMB
+}
+
+// AvgHoldPrice 持仓均价 (包含买入费用, 已扣除现金分红)
+func (a *Account) AvgHoldPrice() float64 {
+	if a.Balance.StockVol <= 0 {
+		return 0
+	}
+	return a.Balance.CostRMB / float64(a.Balance.StockVol)
+}
+
+// 将不在批次记录中的持仓 (例如初始化时已持有的份额) 记录为一个批次, 使批次份额和成本与账户余额一致
+func (a *Account) syncLots() {
+	vol, cost := 0, 0.0
+	for _, lot := range a.Lots {
+		vol, cost = vol+lot.Vol, cost+lot.Cost
+	}
+	if untracked := a.Balance.StockVol - vol; untracked > 0 {
+		lot := PositionLot{ID: a.nextLotID(), Vol: untracked, Cost: a.Balance.CostRMB - cost}
+		a.Lots = append([]PositionLot{lot}, a.Lots...)
+	}
+	if untracked := vol - a.Balance.StockVol; untracked > 0 {
+		a.takeLots(untracked, 0, 0)
+	}
+}
+
+// 记录新买入的持仓批次, cost为包含费用的买入成本
+func (a *Account) addLot(timestamp int64, vol int, cost float64) {
+	a.Lots = append(a.Lots, PositionLot{ID: a.nextLotID(), Timestamp: timestamp, TradeDay: tradeDay(timestamp), Vol: vol, Cost: cost})
+}
+
+// 按照持仓成本计算方式扣减持仓批次, 返回扣减份额的持仓成本, lotID大于0时优先扣减指定批次
+func (a *Account) takeLots(vol int, lotID int, timestamp int64) (cost float64) {
+	method := a.CostMethod
+	if lotID > 0 {
+		method = CostSpecific
+	}
+	order := make([]int, len(a.Lots)) // 扣减顺序
+	for i := range order {
+		order[i] = i
+	}
+	if method == CostSpecific {
+		today := tradeDay(timestamp)
+		rank := func(lot PositionLot) (isSpecified bool, isSellable bool, unitCost float64) {
+			return lot.ID == lotID, lot.TradeDay != today, lot.Cost / float64(lot.Vol)
+		}
+		sort.SliceStable(order, func(i, j int) bool {
+			iSpecified, iSellable, iCost := rank(a.Lots[order[i]])
+			jSpecified, jSellable, jCost := rank(a.Lots[order[j]])
+			if iSpecified != jSpecified {
+				return iSpecified
+			}
+			if iSellable != jSellable {
+				return iSellable
+			}
+			return iCost > jCost
+		})
+	}
+
+	total, totalCost := 0, 0.0
+	for _, lot := range a.Lots {
+		total, totalCost = total+lot.Vol, totalCost+lot.Cost
+	}
+	remain := vol
+	for _, i := range order {
+		if remain <= 0 {
+			break
+		}
+		take := a.Lots[i].Vol
+		if take > remain {
+			take = remain
+		}
+		part := a.Lots[i].Cost * float64(take) / float64(a.Lots[i].Vol)
+		a.Lots[i].Vol -= take
+		a.Lots[i].Cost -= part
+		cost += part
+		remain -= take
+	}
+	lots := a.Lots[:0]
+	for _, lot := range a.Lots {
+		if lot.Vol > 0 {
+			lots = append(lots, lot)
+		}
+	}
+	a.Lots = lots
+
+	if method == CostAverage && total > 0 { // 剩余批次按平均成本重新计算
+		avg := totalCost / float64(total)
+		cost = avg * float64(vol-remain)
+		for i := range a.Lots {
+			a.Lots[i].Cost = avg * float64(a.Lots[i].Vol)
+		}
+	}
+	return
+}
+
+// 按份额比例调整各批次的持仓成本, 例如现金分红摊薄成本或合股后保持成本不变
+func (a *Account) spreadCost(amount float64) {
+	vol := 0
+	for _, lot := range a.Lots {
+		vol += lot.Vol
+	}
+	if vol <= 0 {
+		return
+	}
+	for i := range a.Lots {
+		a.Lots[i].Cost += amount * float64(a.Lots[i].Vol) / float64(vol)
+	}
+}
+
+// 送转或合股后按比例调整各批次的份额, 批次的成本和买入时间不变, 返回总份额的变化
+// 每个批次不足一股的部分舍去, 舍去后份额为0的批次成本分摊到其他批次
+func (a *Account) scaleLots(bonus float64) (change int) {
+	lots, dropped := a.Lots[:0], 0.0
+	for _, lot := range a.Lots {
+		vol := int(math.Floor(float64(lot.Vol)*(1+bonus) + 1e-9))
+		change += vol - lot.Vol
+		if vol <= 0 {
+			dropped += lot.Cost
+			continue
+		}
+		lot.Vol = vol
+		lots = append(lots, lot)
+	}
+	a.Lots = lots
+	a.spreadCost(dropped)
+	return
+}
+
+// 获取新的持仓批次编号
+func (a *Account) nextLotID() int {
+	a.LotSeq++
+	return a.LotSeq
+}
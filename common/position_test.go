@@ -0,0 +1,68 @@
+package common
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestPositionAccounting(t *testing.T) {
+	nodes := []KLineNode{mockNode("2022-06-01", 10), mockNode("2022-06-02", 10.5), mockNode("2022-06-06", 11)}
+	newAccount := func(method CostMethod) *Account {
+		account := mockAccount("Position", 100000)
+		account.CostMethod = method
+		account.UpdateStat(nodes[0])
+		account.Trad(ModeBuy, 10, 100, nodes[0])
+		account.UpdateStat(nodes[1])
+		account.Trad(ModeBuy, 10.5, 100, nodes[1])
+		account.UpdateStat(nodes[2])
+		return account
+	}
+	for _, c := range []struct {
+		method CostMethod
+		pnl    float64
+		cost   float64
+	}{{CostFIFO, 100, 1050}, {CostAverage, 75, 1025}, {CostSpecific, 50, 1000}} {
+		account := newAccount(c.method)
+		if err := account.Trad(ModeShell, 11, 100, nodes[2]); err != nil {
+			t.Fatalf("sell fail: method=%s err=%v", c.method, err)
+		}
+		r := account.TradLog[2]
+		if math.Abs(r.RealizedPnL-c.pnl) > 1e-6 || math.Abs(account.TradStat.RealizedPnL-c.pnl) > 1e-6 || math.Abs(account.Balance.CostRMB-c.cost) > 1e-6 {
+			t.Fatalf("unexpect pnl: method=%s record=%+v cost=%.2f", c.method, r, account.Balance.CostRMB)
+		}
+		if math.Abs(account.UnrealizedPnL()-(1100-c.cost)) > 1e-6 || math.Abs(account.AvgHoldPrice()-c.cost/100) > 1e-6 {
+			t.Fatalf("unexpect unrealized pnl: method=%s pnl=%.2f avg=%.3f", c.method, account.UnrealizedPnL(), account.AvgHoldPrice())
+		}
+	}
+
+	// 指定卖出第一个批次
+	account := newAccount(CostSpecific)
+	if err := account.TradLot(account.Lots[0].ID, 11, 100, nodes[2]); err != nil || account.TradLog[2].RealizedPnL != 100 {
+		t.Fatalf("unexpect specific lot sell: err=%v log=%+v", err, account.TradLog)
+	}
+	if err := account.TradLot(99, 11, 100, nodes[2]); !errors.Is(err, ErrInvalidParams) {
+		t.Fatalf("expect unknown lot rejected: err=%v", err)
+	}
+
+	// 送转股按比例增加各批次份额, 成本和买入时间不变
+	account = newAccount(CostFIFO)
+	lots := append([]PositionLot(nil), account.Lots...)
+	account.ApplyExRight(nodes[2].Timestamp, 0, 0.5)
+	if account.Balance.StockVol != 300 || account.TradStat.BonusVol != 100 || len(account.Lots) != 2 {
+		t.Fatalf("unexpect bonus result: balance=%+v lots=%+v", account.Balance, account.Lots)
+	}
+	for i, lot := range account.Lots {
+		if lot.ID != lots[i].ID || lot.Vol != 150 || lot.Cost != lots[i].Cost || lot.TradeDay != lots[i].TradeDay {
+			t.Fatalf("expect lot scaled with cost kept: before=%+v after=%+v", lots[i], lot)
+		}
+	}
+
+	// 初始持仓按初始成本计算
+	account = mockAccount("Init", 100000)
+	account.Balance.StockVol, account.Balance.CostRMB = 100, 900
+	account.UpdateStat(nodes[2])
+	if err := account.Trad(ModeShell, 11, 100, nodes[2]); err != nil || account.TradLog[0].RealizedPnL != 200 || account.Balance.CostRMB != 0 {
+		t.Fatalf("unexpect initial position pnl: err=%v log=%+v", err, account.TradLog)
+	}
+}
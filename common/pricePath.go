@@ -0,0 +1,59 @@
+package common
+
+// PricePath 节点内的价格路径模型, 返回价格在节点内依次经过的关键点
+type PricePath interface {
+	Path(moment KLineNode) []float64
+}
+
+// OHLCPath 根据节点方向推测价格路径: 阳线为 开盘→最低→最高→收盘, 阴线为 开盘→最高→最低→收盘
+type OHLCPath struct{}
+
+// Path 获取节点的价格路径
+func (o *OHLCPath) Path(moment KLineNode) []float64 {
+	if moment.End >= moment.Start {
+		return []float64{moment.Start, moment.Bottom, moment.Top, moment.End}
+	}
+	return []float64{moment.Start, moment.Top, moment.Bottom, moment.End}
+}
+
+// SubBarPath 使用低级别k线数据模拟价格路径, 例如用15分钟数据模拟日线节点内的走势
+// 日线节点使用同一交易日的子节点, 分钟节点使用上一个节点之后到当前节点的子节点, 没有子节点时按OHLCPath处理
+// 需要按时间顺序依次调用Path
+type SubBarPath struct {
+	Bars []KLineNode // 低级别k线, 按时间升序排列
+
+	idx           int   // 下一个未使用的子节点
+	lastTimestamp int64 // 上一个节点的时间
+}
+
+// NewSubBarPath 使用低级别k线数据创建价格路径模型
+func NewSubBarPath(bars []KLineNode) *SubBarPath {
+	return &SubBarPath{Bars: bars}
+}
+
+// Path 获取节点的价格路径
+func (s *SubBarPath) Path(moment KLineNode) (path []float64) {
+	isDay := len(moment.TimeDesc) <= len("2006-01-02")
+	day := tradeDay(moment.Timestamp)
+	inMoment := func(bar KLineNode) bool {
+		if isDay {
+			return tradeDay(bar.Timestamp) == day
+		}
+		return bar.Timestamp > s.lastTimestamp && bar.Timestamp <= moment.Timestamp
+	}
+	for s.idx < len(s.Bars) && !inMoment(s.Bars[s.idx]) && s.Bars[s.idx].Timestamp < moment.Timestamp {
+		s.idx++ // 跳过不属于任何节点的子节点
+	}
+	ohlc := &OHLCPath{}
+	for ; s.idx < len(s.Bars) && inMoment(s.Bars[s.idx]); s.idx++ {
+		path = append(path, ohlc.Path(s.Bars[s.idx])...)
+	}
+	if s.lastTimestamp == 0 && !isDay {
+		path = nil // 第一个分钟节点无法确定开始时间
+	}
+	s.lastTimestamp = moment.Timestamp
+	if len(path) == 0 {
+		return ohlc.Path(moment)
+	}
+	return path
+}
@@ -0,0 +1,44 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestExecuteEntrustPath(t *testing.T) {
+	moment := mockNode("2022-06-01", 10)
+	moment.End, moment.Bottom = 10.4, 9.6
+	ts := moment.Timestamp
+	account := mockAccount("Path", 100000)
+	account.T0, account.Balance.StockVol = true, 1000
+	account.CreateEntrust(ModeShell, 10.3, 100, ts, 0)
+	account.CreateEntrust(ModeBuy, 9.8, 100, ts, 0)
+
+	// 阳线按 开盘→最低→最高→收盘 运行: 先买入9.8, 回调创建10.0的卖单, 上涨过程中依次卖出10.0和10.3
+	var fills []float64
+	count := account.ExecuteEntrustPath(moment, func(mode OpMode, record Entrust) {
+		fills = append(fills, record.Price)
+		if mode == ModeBuy {
+			account.CreateEntrust(ModeShell, 10.0, 100, ts, 0)
+		}
+	})
+	if count != 3 || len(fills) != 3 || fills[0] != 9.8 || fills[1] != 10.0 || fills[2] != 10.3 {
+		t.Fatalf("unexpect fills: count=%d fills=%v", count, fills)
+	}
+
+	// 使用低级别数据模拟路径: 先涨后跌的日线节点先成交卖单
+	subBars := []KLineNode{
+		{Timestamp: ts + 10*3600, Start: 10, End: 10.5, Top: 10.5, Bottom: 10},
+		{Timestamp: ts + 14*3600, Start: 10.5, End: 9.6, Top: 10.5, Bottom: 9.6},
+	}
+	account.PricePath = NewSubBarPath(subBars)
+	account.CreateEntrust(ModeShell, 10.4, 100, ts, 0)
+	account.CreateEntrust(ModeBuy, 9.7, 100, ts, 0)
+	fills = nil
+	moment.End = 9.7
+	account.ExecuteEntrustPath(moment, func(mode OpMode, record Entrust) {
+		fills = append(fills, record.Price)
+	})
+	if len(fills) != 2 || fills[0] != 10.4 || fills[1] != 9.7 {
+		t.Fatalf("unexpect sub bar fills: %v", fills)
+	}
+}
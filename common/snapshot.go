@@ -0,0 +1,177 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// SnapshotVersion 当前账号快照的格式版本, 快照结构发生不兼容的变化时增加
+const SnapshotVersion = 1
+
+// AccountSnapshot 账号快照, 记录账号的完整状态, 用于保存模拟结果并在之后的数据上继续模拟
+// FeeModel, Execution, PricePath, CashYield 属于运行配置, 不保存在快照中, 恢复后需要重新设置
+type AccountSnapshot struct {
+	Version     int        `json:"version"`     // 快照格式版本
+	SaveTime    int64      `json:"saveTime"`    // 保存时间
+	Account     Account    `json:"account"`     // 账号基本信息, 余额, 持仓批次和日志
+	Setting     Setting    `json:"setting"`     // 买入卖出锁
+	BuyEntrust  []Entrust  `json:"buyEntrust"`  // 买入委托单
+	SellEntrust []Entrust  `json:"sellEntrust"` // 卖出委托单
+	Closed      []Entrust  `json:"closed"`      // 已成交, 撤销或失效的委托单
+	Rejected    []Entrust  `json:"rejected"`    // 被拒绝的委托单
+	EntrustSeq  int        `json:"entrustSeq"`  // 已分配的最大委托编号
+	LotSeq      int        `json:"lotSeq"`      // 已分配的最大持仓批次编号
+	LastPrize   *KLineNode `json:"lastPrize"`   // 最新股票状况
+	LastDeal    *KLineNode `json:"lastDeal"`    // 上次交易时的股票状况
+}
+
+// Snapshot 获取账号当前状态的快照, 快照不受账号之后的变化影响
+func (a *Account) Snapshot() (snapshot AccountSnapshot) {
+	account := a.clone()
+	account.FeeModel, account.Execution, account.PricePath, account.CashYield, account.portfolio = nil, nil, nil, nil, nil
+	snapshot = AccountSnapshot{
+		Version:     SnapshotVersion,
+		SaveTime:    time.Now().Unix(),
+		Account:     account,
+		Setting:     account.Setting,
+		BuyEntrust:  account.BuyEntrust,
+		SellEntrust: account.SellEntrust,
+		Closed:      account.Closed,
+		Rejected:    account.Rejected,
+		EntrustSeq:  account.EntrustSeq,
+		LotSeq:      account.LotSeq,
+		LastPrize:   account.LastPrize,
+		LastDeal:    account.LastDeal,
+	}
+	return
+}
+
+// Restore 根据快照恢复账号, 快照版本不受支持时返回错误
+func (s AccountSnapshot) Restore() (account *Account, err error) {
+	if s.Version <= 0 || s.Version > SnapshotVersion {
+		err = fmt.Errorf("unexpect snapshot version: version=%d support=%d", s.Version, SnapshotVersion)
+		return
+	}
+	restored := s.Account
+	restored.Setting = s.Setting
+	restored.BuyEntrust = s.BuyEntrust
+	restored.SellEntrust = s.SellEntrust
+	restored.Closed = s.Closed
+	restored.Rejected = s.Rejected
+	restored.EntrustSeq = s.EntrustSeq
+	restored.LotSeq = s.LotSeq
+	restored.LastPrize = s.LastPrize
+	restored.LastDeal = s.LastDeal
+	restored = restored.clone() // 同一个快照可以多次恢复, 恢复的账号之间互不影响
+	return &restored, nil
+}
+
+// 复制账号, 切片和指针字段指向新的副本
+func (a *Account) clone() (c Account) {
+	c = *a
+	if a.Rule != nil {
+		rule := *a.Rule
+		c.Rule = &rule
+	}
+	if a.Margin != nil {
+		margin := *a.Margin
+		c.Margin = &margin
+	}
+	if a.LastPrize != nil {
+		node := *a.LastPrize
+		c.LastPrize = &node
+	}
+	if a.LastDeal != nil {
+		node := *a.LastDeal
+		c.LastDeal = &node
+	}
+	c.TradLog = append([]TradRecord(nil), a.TradLog...)
+	c.ActionLog = append([]Action(nil), a.ActionLog...)
+	c.Lots = append([]PositionLot(nil), a.Lots...)
+	c.Equity = append([]EquityPoint(nil), a.Equity...)
+	c.BuyEntrust = append([]Entrust(nil), a.BuyEntrust...)
+	c.SellEntrust = append([]Entrust(nil), a.SellEntrust...)
+	c.Closed = append([]Entrust(nil), a.Closed...)
+	c.Rejected = append([]Entrust(nil), a.Rejected...)
+	return
+}
+
+// SaveAccount 把账号快照以json格式写入w
+func SaveAccount(w io.Writer, account *Account) (err error) {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(account.Snapshot()); err != nil {
+		err = fmt.Errorf("encode snapshot fail: %v", err)
+	}
+	return
+}
+
+// LoadAccount 从r读取SaveAccount保存的账号快照并恢复账号
+// 兼容不带版本号的旧格式, 即直接序列化的Account (只包含基本信息, 余额和日志)
+func LoadAccount(r io.Reader) (account *Account, err error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		err = fmt.Errorf("read snapshot fail: %v", err)
+		return
+	}
+	var snapshot AccountSnapshot
+	if err = json.Unmarshal(raw, &snapshot); err != nil {
+		err = fmt.Errorf("decode snapshot fail: %v", err)
+		return
+	}
+	if snapshot.Version == 0 { // 旧格式
+		if err = json.Unmarshal(raw, &snapshot.Account); err != nil {
+			err = fmt.Errorf("decode account fail: %v", err)
+			return
+		}
+		snapshot.Version = SnapshotVersion
+	}
+	if err = fixLegacyField(raw, &snapshot); err != nil {
+		return
+	}
+	return snapshot.Restore()
+}
+
+// SaveAccountFile 把账号快照保存到文件
+func SaveAccountFile(path string, account *Account) (err error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	return SaveAccount(file, account)
+}
+
+// LoadAccountFile 从文件读取账号快照并恢复账号
+func LoadAccountFile(path string) (account *Account, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	return LoadAccount(file)
+}
+
+// 旧版本使用 targetSock 作为TargetStock的json标签, 读取时兼容
+func fixLegacyField(raw []byte, snapshot *AccountSnapshot) (err error) {
+	if snapshot.Account.TargetStock != "" {
+		return
+	}
+	var legacy struct {
+		TargetSock string `json:"targetSock"`
+		Account    struct {
+			TargetSock string `json:"targetSock"`
+		} `json:"account"`
+	}
+	if err = json.Unmarshal(raw, &legacy); err != nil {
+		return fmt.Errorf("decode snapshot fail: %v", err)
+	}
+	snapshot.Account.TargetStock = legacy.TargetSock
+	if legacy.Account.TargetSock != "" {
+		snapshot.Account.TargetStock = legacy.Account.TargetSock
+	}
+	return
+}
@@ -0,0 +1,45 @@
+package common
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	moment := mockNode("2022-06-01", 10)
+	account := mockAccount("Snapshot", 100000)
+	account.UpdateStat(moment)
+	account.Trad(ModeBuy, 10, 100, moment)
+	account.CreateEntrust(ModeBuy, 9.5, 100, moment.Timestamp, 0)
+
+	// 保存后恢复, 委托单和最新价格保持不变
+	var buf bytes.Buffer
+	if err := SaveAccount(&buf, account); err != nil {
+		t.Fatalf("save fail: err=%v", err)
+	}
+	restored, err := LoadAccount(&buf)
+	if err != nil {
+		t.Fatalf("load fail: err=%v", err)
+	}
+	if len(restored.OpenEntrusts()) != 1 || restored.LastPrize == nil || restored.Balance != account.Balance {
+		t.Fatalf("unexpect restored account: entrust=%+v balance=%+v", restored.OpenEntrusts(), restored.Balance)
+	}
+
+	// 快照与账号之后的变化互不影响
+	snapshot := account.Snapshot()
+	account.LastPrize.End, account.BuyEntrust[0].Price = -1, -1
+	account.TradLog = append(account.TradLog[:0], TradRecord{})
+	if snapshot.LastPrize.End < 0 || snapshot.BuyEntrust[0].Price < 0 || snapshot.Account.TradLog[0].Prize == 0 {
+		t.Fatalf("expect snapshot not affected by account changes")
+	}
+
+	// 兼容旧格式, 拒绝不支持的版本
+	legacy, err := LoadAccount(strings.NewReader(`{"name":"Old","InitFundRMB":1000,"targetSock":"600036"}`))
+	if err != nil || legacy.Name != "Old" || legacy.TargetStock != "600036" {
+		t.Fatalf("unexpect legacy account: err=%v account=%+v", err, legacy)
+	}
+	if _, err = LoadAccount(strings.NewReader(`{"version":99,"account":{"name":"New"}}`)); err == nil {
+		t.Fatalf("expect error on unsupported snapshot version")
+	}
+}
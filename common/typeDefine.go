@@ -14,14 +14,21 @@ type Setting struct {
 
 // TradInfo 账户交易数据概述
 type TradInfo struct {
-	BuyCounter  int     `json:"buyCounter"`   // 买入次数
-	SellCounter int     `json:"shellCounter"` // 卖出次数
-	MaxVol      int     `json:"maxVol"`       // 历史最高持仓
-	MinVol      int     `json:"minVol"`       // 历史最低持仓
-	MaxCost     float64 `json:"maxCost"`      // 历史最高持仓成本
-	MinCost     float64 `json:"minCost"`      // 历史最低持仓成本
-	MaxValue    float64 `json:"maxValue"`     // 历史最高账户资产
-	MinValue    float64 `json:"minValue"`     // 历史最低账户资产
+	BuyCounter     int     `json:"buyCounter"`     // 买入次数
+	SellCounter    int     `json:"shellCounter"`   // 卖出次数
+	MaxVol         int     `json:"maxVol"`         // 历史最高持仓
+	MinVol         int     `json:"minVol"`         // 历史最低持仓
+	MaxCost        float64 `json:"maxCost"`        // 历史最高持仓成本
+	MinCost        float64 `json:"minCost"`        // 历史最低持仓成本
+	MaxValue       float64 `json:"maxValue"`       // 历史最高账户资产
+	MinValue       float64 `json:"minValue"`       // 历史最低账户资产
+	AdjustGain     float64 `json:"adjustGain"`     // 除权除息补偿 (不复权价格无法体现的分红送转收益)
+	DividendRMB    float64 `json:"dividendRmb"`    // 累计收到的现金分红
+	BonusVol       int     `json:"bonusVol"`       // 累计获得的送转股份额
+	TotalFee       float64 `json:"totalFee"`       // 累计交易费用
+	RealizedPnL    float64 `json:"realizedPnl"`    // 累计已实现盈亏 (卖出金额-卖出费用-持仓成本)
+	MarginInterest float64 `json:"marginInterest"` // 累计融资利息和融券费用
+	CashInterest   float64 `json:"cashInterest"`   // 累计闲置现金收益 (与交易盈亏分开统计)
 }
 
 // TradRecord 交易记录
@@ -30,6 +37,47 @@ type TradRecord struct {
 	Mode      OpMode  `json:"mode"`      // 买或卖
 	Prize     float64 `json:"prize"`     // 成交价
 	Vol       int     `json:"vol"`       // 成交量
+	Fee       float64 `json:"fee"`       // 交易费用
+
+	EntrustPrize float64 `json:"entrustPrize"`    // 委托价 (与成交价的差额为滑点)
+	EntrustVol   int     `json:"entrustVol"`      // 委托份额 (部分成交时大于成交量)
+	RealizedPnL  float64 `json:"realizedPnl"`     // 卖出或买券还券时的已实现盈亏 (买入时为0)
+	Short        bool    `json:"short,omitempty"` // 是否为融券卖出或买券还券
+}
+
+// PositionLot 持仓批次, 按买入时间记录, 用于计算T+1制度下的可卖份额和卖出时的持仓成本
+type PositionLot struct {
+	ID        int     `json:"id"`        // 批次编号
+	Timestamp int64   `json:"timestamp"` // 买入时间 (初始持仓为0)
+	TradeDay  string  `json:"tradeDay"`  // 买入所在交易日, 格式: 2006-01-02
+	Vol       int     `json:"vol"`       // 剩余份额
+	Cost      float64 `json:"cost"`      // 剩余份额的持仓成本 (包含买入费用, 已扣除现金分红)
+}
+
+// PriceLimit 当日涨跌停价格
+type PriceLimit struct {
+	TradeDay  string  `json:"tradeDay"`  // 所属交易日, 格式: 2006-01-02
+	PrevClose float64 `json:"prevClose"` // 昨收价 (除权除息日为除权参考价)
+	Up        float64 `json:"up"`        // 涨停价 (0=不限制)
+	Down      float64 `json:"down"`      // 跌停价
+}
+
+// IsValid 是否存在有效的涨跌停价格
+func (p PriceLimit) IsValid() bool {
+	return p.Up > 0 && p.Down > 0
+}
+
+// Contains 判断价格是否在涨跌停价格范围内
+func (p PriceLimit) Contains(prize float64) bool {
+	return !p.IsValid() || (prize <= p.Up+1e-9 && prize >= p.Down-1e-9)
+}
+
+// IsLocked 判断节点是否为一字涨停或一字跌停 (全天只有一个价格, 无法判断排队成交情况)
+func (p PriceLimit) IsLocked(moment KLineNode) bool {
+	if !p.IsValid() || moment.Top != moment.Bottom {
+		return false
+	}
+	return moment.Top >= p.Up-1e-9 || moment.Bottom <= p.Down+1e-9
 }
 
 // Action 账号动作日志
@@ -41,9 +89,28 @@ type Action struct {
 
 // Entrust 委托单
 type Entrust struct {
-	StarTime int64   `json:"StarTime"` // 委托时间
-	DeadTime int64   `json:"deadTime"` // 失效时间 (0=一直有效)
-	DealTime int64   `json:"dealTime"` // 成交时间或失效时间 (0=未成交且仍有效)
-	Price    float64 `json:"price"`    // 委托价
-	Vol      int     `json:"vol"`      // 交易份数
+	ID       int           `json:"id"`       // 委托编号, 由账号创建委托时分配
+	Status   EntrustStatus `json:"status"`   // 委托状态
+	StarTime int64         `json:"StarTime"` // 委托时间
+	DeadTime int64         `json:"deadTime"` // 失效时间 (0=一直有效)
+	DealTime int64         `json:"dealTime"` // 全部成交, 撤销或失效的时间 (0=仍在等待成交)
+	Price    float64       `json:"price"`    // 委托价
+	Vol      int           `json:"vol"`      // 交易份数
+
+	FilledVol int `json:"filledVol"` // 已成交份数 (部分成交时小于Vol, 剩余部分继续等待成交)
+
+	Mode        OpMode      `json:"mode"`        // 买或卖
+	Kind        EntrustKind `json:"kind"`        // 委托类型 (空=限价条件单)
+	StopPrice   float64     `json:"stopPrice"`   // 触发价 (止损单和止损限价单)
+	TrailRate   float64     `json:"trailRate"`   // 跟踪止损回撤比例
+	TrailAmount float64     `json:"trailAmount"` // 跟踪止损回撤金额
+	Extreme     float64     `json:"extreme"`     // 跟踪止损期间的最高价(卖出)或最低价(买入)
+	Triggered   bool        `json:"triggered"`   // 止损限价单是否已触发
+	Group       int         `json:"group"`       // 二选一委托组编号, 组内一个委托成交后撤销其他委托 (0=不分组)
+	After       int         `json:"after"`       // 括号单等待生效的建仓委托组编号 (0=已生效)
+}
+
+// RemainVol 获取委托单未成交的份数
+func (e Entrust) RemainVol() int {
+	return e.Vol - e.FilledVol
 }
@@ -0,0 +1,84 @@
+package dao
+
+import (
+	"fmt"
+	"math"
+)
+
+// AdjustFactor 后复权因子, 从Timestamp对应的节点开始生效: 后复权价 = 不复权价 * Factor
+type AdjustFactor struct {
+	Timestamp int64   `json:"timestamp"`
+	TimeDesc  string  `json:"timeDesc"` // 除权除息日
+	Factor    float64 `json:"factor"`   // 累计复权因子, 第一个节点之前为1
+}
+
+// CalcAdjustFactors 根据不复权数据计算复权因子
+// 除权除息日的涨跌额是相对交易所公布的除权参考价计算的, 参考价与上一日收盘价不一致时即发生了除权除息
+func CalcAdjustFactors(raw KLineData) (factors []AdjustFactor, err error) {
+	if raw.Adjust != AdjustNone {
+		err = fmt.Errorf("expect unadjusted data but got %s", raw.Adjust)
+		return
+	}
+	factor := 1.0
+	for i := 1; i < len(raw.KLines); i++ {
+		prev, node := raw.KLines[i-1], raw.KLines[i]
+		refPrice := node.End - node.PriceRise // 除权参考价
+		if refPrice <= 0 || prev.End <= 0 || math.Abs(refPrice-prev.End) < 0.0005 {
+			continue
+		}
+		factor *= prev.End / refPrice
+		factors = append(factors, AdjustFactor{Timestamp: node.Timestamp, TimeDesc: node.TimeDesc, Factor: factor})
+	}
+	return
+}
+
+// GetFactor 获取指定时间生效的复权因子
+func GetFactor(factors []AdjustFactor, timestamp int64) float64 {
+	factor := 1.0
+	for _, item := range factors {
+		if item.Timestamp > timestamp {
+			break
+		}
+		factor = item.Factor
+	}
+	return factor
+}
+
+// ConvertAdjust 使用复权因子在不复权, 前复权, 后复权数据之间转换 (等比复权, 不会出现负数价格)
+func ConvertAdjust(data KLineData, factors []AdjustFactor, target AdjustMode) (result KLineData, err error) {
+	if target != AdjustNone && target != AdjustForward && target != AdjustBackward {
+		err = fmt.Errorf("unexpect adjust mode: %d", target)
+		return
+	}
+	if data.Adjust == AdjustUnknown {
+		err = fmt.Errorf("can not convert data with unknown adjust mode: code=%s", data.Code)
+		return
+	}
+	result = data
+	result.Adjust = target
+	result.KLines = append(data.KLines[:0:0], data.KLines...)
+	if len(data.KLines) == 0 || data.Adjust == target {
+		return
+	}
+	lastFactor := GetFactor(factors, data.KLines[len(data.KLines)-1].Timestamp)
+	scaleOf := func(mode AdjustMode, factor float64) float64 {
+		switch mode {
+		case AdjustForward:
+			return factor / lastFactor
+		case AdjustBackward:
+			return factor
+		}
+		return 1
+	}
+	for i := range result.KLines {
+		node := &result.KLines[i]
+		factor := GetFactor(factors, node.Timestamp)
+		scale := scaleOf(target, factor) / scaleOf(data.Adjust, factor)
+		node.Start = roundTo(node.Start*scale, 3)
+		node.End = roundTo(node.End*scale, 3)
+		node.Top = roundTo(node.Top*scale, 3)
+		node.Bottom = roundTo(node.Bottom*scale, 3)
+		node.PriceRise = roundTo(node.PriceRise*scale, 3)
+	}
+	return
+}
@@ -0,0 +1,88 @@
+package dao
+
+import (
+	"github.com/BlackCarDriver/StockMaster/common"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// 构造不复权数据, 第三个节点每股派息0.5元
+func mockRawKLine() KLineData {
+	nodes := []common.KLineNode{
+		{TimeDesc: "2022-06-01", Start: 10.0, End: 10.0, Top: 10.1, Bottom: 9.9, PriceRise: 0},
+		{TimeDesc: "2022-06-02", Start: 10.0, End: 10.2, Top: 10.3, Bottom: 9.9, PriceRise: 0.2},
+		{TimeDesc: "2022-06-06", Start: 9.7, End: 9.9, Top: 10.0, Bottom: 9.6, PriceRise: 0.2},
+		{TimeDesc: "2022-06-07", Start: 9.9, End: 10.1, Top: 10.2, Bottom: 9.8, PriceRise: 0.2},
+	}
+	for i := range nodes {
+		nodes[i].Timestamp, _ = ParseKLineTime(nodes[i].TimeDesc)
+	}
+	data := KLineData{Code: "600036", Adjust: AdjustNone, KLines: nodes}
+	data.ResetSummary()
+	return data
+}
+
+func TestConvertAdjust(t *testing.T) {
+	raw := mockRawKLine()
+	factors, err := CalcAdjustFactors(raw)
+	if err != nil {
+		t.Fatalf("calc factors fail: err=%v", err)
+	}
+	if len(factors) != 1 || factors[0].TimeDesc != "2022-06-06" || math.Abs(factors[0].Factor-10.2/9.7) > 1e-9 {
+		t.Fatalf("unexpect factors: %+v", factors)
+	}
+
+	backward, err := ConvertAdjust(raw, factors, AdjustBackward)
+	if err != nil {
+		t.Fatalf("convert fail: err=%v", err)
+	}
+	if backward.Adjust != AdjustBackward || backward.KLines[0].End != 10.0 || backward.KLines[2].End != roundTo(9.9*10.2/9.7, 3) {
+		t.Errorf("unexpect backward data: %+v", backward.KLines)
+	}
+	forward, err := ConvertAdjust(backward, factors, AdjustForward)
+	if err != nil {
+		t.Fatalf("convert fail: err=%v", err)
+	}
+	if forward.KLines[3].End != 10.1 || forward.KLines[0].End != roundTo(10.0*9.7/10.2, 3) {
+		t.Errorf("unexpect forward data: %+v", forward.KLines)
+	}
+	for i, node := range forward.KLines {
+		if node.Bottom <= 0 || (i > 0 && math.Abs(node.End-node.PriceRise-forward.KLines[i-1].End) > 0.002) {
+			t.Errorf("unexpect forward node: %+v", node)
+		}
+	}
+	restore, err := ConvertAdjust(forward, factors, AdjustNone)
+	if err != nil {
+		t.Fatalf("convert fail: err=%v", err)
+	}
+	for i := range restore.KLines {
+		if math.Abs(restore.KLines[i].End-raw.KLines[i].End) > 0.001 {
+			t.Errorf("node %d not restore: got=%v expect=%v", i, restore.KLines[i].End, raw.KLines[i].End)
+		}
+	}
+}
+
+func TestCalcActionFactors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "600036_actions.json")
+	content := `{"code":"600036","actions":[{"exDate":"2022-06-06","cash":0.5,"desc":"10派5元"},{"exDate":"2021-07-01","cash":1.5}]}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("write fail: err=%v", err)
+	}
+	actions, err := NewFileProvider(filepath.Dir(path)).FetchCorporateActions("1.600036")
+	if err != nil || len(actions) != 2 || actions[0].ExDate != "2021-07-01" {
+		t.Fatalf("unexpect actions: %+v err=%v", actions, err)
+	}
+
+	// 数据开始之前的事件被忽略, 结果与根据涨跌额推算的复权因子一致
+	raw := mockRawKLine()
+	factors, err := CalcActionFactors(raw, actions)
+	if err != nil {
+		t.Fatalf("calc factors fail: err=%v", err)
+	}
+	expect, _ := CalcAdjustFactors(raw)
+	if len(factors) != 1 || factors[0].Timestamp != expect[0].Timestamp || math.Abs(factors[0].Factor-expect[0].Factor) > 1e-9 {
+		t.Fatalf("unexpect factors: got=%+v expect=%+v", factors, expect)
+	}
+}
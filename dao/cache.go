@@ -0,0 +1,206 @@
+package dao
+
+import (
+	"errors"
+	"fmt"
+	"github.com/BlackCarDriver/StockMaster/common"
+	"math"
+	"os"
+	"sort"
+	"time"
+)
+
+// ErrKLineConflict 缓存与新数据在重叠时段的节点不一致 (例如历史数据被重新复权)
+var ErrKLineConflict = errors.New("kline conflict with cache")
+
+const cacheOverlapNum = 5 // 增量更新时与缓存重叠的节点数量, 用于校验历史数据是否变化
+
+// KLineCache k线数据的本地磁盘缓存, 按 (代码, 周期, 复权方式) 分文件保存, 通过Provider增量更新
+// 缓存文件命名与FileProvider一致, 缓存目录可直接作为FileProvider的数据目录
+type KLineCache struct {
+	Dir      string
+	Provider Provider
+	files    *FileProvider
+}
+
+// NewKLineCache 创建缓存, 数据保存在dir目录, 缺少的数据从provider获取
+func NewKLineCache(dir string, provider Provider) *KLineCache {
+	return &KLineCache{
+		Dir:      dir,
+		Provider: provider,
+		files:    NewFileProvider(dir),
+	}
+}
+
+// Load 读取缓存数据, 缓存不存在时exist为false
+func (c *KLineCache) Load(code string, period KLinePeriod, adjust AdjustMode) (data KLineData, exist bool, err error) {
+	path := c.files.MockDataPath(code, period, adjust)
+	if _, err = os.Stat(path); os.IsNotExist(err) {
+		return data, false, nil
+	}
+	if data, err = ReadKLineMockData(path); err != nil {
+		return
+	}
+	data.Adjust = adjust
+	return data, true, nil
+}
+
+// Save 覆盖保存缓存数据
+func (c *KLineCache) Save(data KLineData, period KLinePeriod, adjust AdjustMode) (err error) {
+	if data.Code == "" {
+		return fmt.Errorf("unexpect empty code")
+	}
+	if err = os.MkdirAll(c.Dir, 0755); err != nil {
+		return
+	}
+	return WriteKLineMockData(c.files.MockDataPath(data.Code, period, adjust), data)
+}
+
+// Invalidate 删除缓存数据
+func (c *KLineCache) Invalidate(code string, period KLinePeriod, adjust AdjustMode) (err error) {
+	err = os.Remove(c.files.MockDataPath(code, period, adjust))
+	if os.IsNotExist(err) {
+		err = nil
+	}
+	return
+}
+
+// LastTimestamp 获取缓存中最后一个节点的时间戳, 没有缓存时返回0
+func (c *KLineCache) LastTimestamp(code string, period KLinePeriod, adjust AdjustMode) (timestamp int64, err error) {
+	data, exist, err := c.Load(code, period, adjust)
+	if err != nil || !exist || len(data.KLines) == 0 {
+		return
+	}
+	return data.KLines[len(data.KLines)-1].Timestamp, nil
+}
+
+// Update 从Provider获取缓存之后的新数据并合并保存, 若历史数据发生变化则丢弃缓存重新获取全部数据
+func (c *KLineCache) Update(secID string, period KLinePeriod, adjust AdjustMode) (data KLineData, err error) {
+	_, code := SplitSecID(secID)
+	cached, exist, err := c.Load(code, period, adjust)
+	if err != nil {
+		log.Warning("load cache fail, fetch all data instead: secID=%s err=%v", secID, err)
+		exist = false
+	}
+	if !exist || len(cached.KLines) == 0 {
+		return c.fetchAll(secID, period, adjust)
+	}
+
+	// 从倒数第N个节点所在日期开始获取, 保证与缓存存在重叠
+	from := cached.KLines[0].TimeDesc
+	if n := len(cached.KLines); n > cacheOverlapNum {
+		from = cached.KLines[n-cacheOverlapNum].TimeDesc
+	}
+	fetched, err := c.Provider.FetchKLine(secID, period, adjust, dateParam(from), "")
+	if err != nil {
+		return
+	}
+	data, err = MergeKLineData(cached, fetched)
+	if errors.Is(err, ErrKLineConflict) {
+		log.Warning("cache is outdated, fetch all data again: secID=%s period=%s adjust=%s err=%v", secID, period, adjust, err)
+		if err = c.Invalidate(code, period, adjust); err != nil {
+			return
+		}
+		return c.fetchAll(secID, period, adjust)
+	}
+	if err != nil {
+		return
+	}
+	err = c.Save(data, period, adjust)
+	return
+}
+
+// FetchKLine 先增量更新缓存, 再返回指定日期范围内的数据
+func (c *KLineCache) FetchKLine(secID string, period KLinePeriod, adjust AdjustMode, from, to string) (data KLineData, err error) {
+	if data, err = c.Update(secID, period, adjust); err != nil {
+		return
+	}
+	return data.FilterByDate(from, to), nil
+}
+
+func (c *KLineCache) fetchAll(secID string, period KLinePeriod, adjust AdjustMode) (data KLineData, err error) {
+	if data, err = c.Provider.FetchKLine(secID, period, adjust, "", ""); err != nil {
+		return
+	}
+	sortKLine(data.KLines)
+	data.ResetSummary()
+	err = c.Save(data, period, adjust)
+	return
+}
+
+// MergeKLineData 将新获取的数据追加到缓存数据之后
+// 与缓存重叠的节点必须完全一致, 否则返回ErrKLineConflict
+func MergeKLineData(cached, fetched KLineData) (merged KLineData, err error) {
+	if cached.Code != "" && fetched.Code != "" && cached.Code != fetched.Code {
+		err = fmt.Errorf("code not match: cached=%s fetched=%s", cached.Code, fetched.Code)
+		return
+	}
+	merged = cached
+	merged.KLines = append(merged.KLines[:0:0], cached.KLines...)
+	if fetched.Code != "" {
+		merged.Code, merged.Name = fetched.Code, fetched.Name
+	}
+
+	sortKLine(fetched.KLines)
+	cachedIdx := make(map[int64]int, len(cached.KLines))
+	for i, node := range cached.KLines {
+		cachedIdx[node.Timestamp] = i
+	}
+	var lastTimestamp int64
+	if n := len(cached.KLines); n > 0 {
+		lastTimestamp = cached.KLines[n-1].Timestamp
+	}
+	for _, node := range fetched.KLines {
+		if node.Timestamp > lastTimestamp || len(cached.KLines) == 0 {
+			if n := len(merged.KLines); n == 0 || node.Timestamp > merged.KLines[n-1].Timestamp {
+				merged.KLines = append(merged.KLines, node)
+			}
+			continue
+		}
+		if node.Timestamp < cached.KLines[0].Timestamp {
+			continue
+		}
+		i, ok := cachedIdx[node.Timestamp]
+		if !ok {
+			err = fmt.Errorf("%w: node %s not found in cache", ErrKLineConflict, node.TimeDesc)
+			return
+		}
+		if !isSameKLineNode(cached.KLines[i], node) {
+			err = fmt.Errorf("%w: node %s changed, cached=%+v fetched=%+v", ErrKLineConflict, node.TimeDesc, cached.KLines[i], node)
+			return
+		}
+	}
+	merged.UpdateTime = time.Now().Unix()
+	merged.ResetSummary()
+	return
+}
+
+// 判断两个节点的价格和成交数据是否一致
+func isSameKLineNode(a, b common.KLineNode) bool {
+	const epsilon = 1e-6
+	pairs := [][2]float64{{a.Start, b.Start}, {a.End, b.End}, {a.Top, b.Top}, {a.Bottom, b.Bottom}, {a.Vol, b.Vol}, {a.Vov, b.Vov}}
+	for _, p := range pairs {
+		if math.Abs(p[0]-p[1]) > epsilon {
+			return false
+		}
+	}
+	return a.TimeDesc == b.TimeDesc
+}
+
+func sortKLine(nodes []common.KLineNode) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].Timestamp < nodes[j].Timestamp
+	})
+}
+
+// 将 2006-01-02 15:04 格式的时间描述转换为接口使用的 20060102 格式
+func dateParam(timeDesc string) string {
+	if len(timeDesc) < len(klineDayLayout) {
+		return ""
+	}
+	t, err := time.Parse(klineDayLayout, timeDesc[:len(klineDayLayout)])
+	if err != nil {
+		return ""
+	}
+	return t.Format("20060102")
+}
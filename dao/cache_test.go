@@ -0,0 +1,72 @@
+package dao
+
+import (
+	"github.com/BlackCarDriver/StockMaster/common"
+	"testing"
+)
+
+// 返回固定数据的内存数据源
+type memoryProvider struct {
+	data  KLineData
+	calls []string
+}
+
+func (m *memoryProvider) FetchKLine(secID string, period KLinePeriod, adjust AdjustMode, from, to string) (KLineData, error) {
+	m.calls = append(m.calls, from)
+	return m.data.FilterByDate(from, to), nil
+}
+
+func TestKLineCacheUpdate(t *testing.T) {
+	full, err := NewFileProvider("./mockdata").FetchKLine("1.510500", PeriodDay, AdjustForward, "", "")
+	if err != nil {
+		t.Fatalf("read mock data fail: err=%v", err)
+	}
+	provider := &memoryProvider{data: full.FilterByDate("", "20220831")}
+	cache := NewKLineCache(t.TempDir(), provider)
+
+	// 首次获取全部数据
+	data, err := cache.Update("1.510500", PeriodDay, AdjustForward)
+	if err != nil || data.To != "2022-08-31" {
+		t.Fatalf("first update fail: to=%s err=%v", data.To, err)
+	}
+
+	// 增量获取新数据, 只请求与缓存末尾重叠的部分
+	provider.data = full
+	data, err = cache.Update("1.510500", PeriodDay, AdjustForward)
+	if err != nil {
+		t.Fatalf("incremental update fail: err=%v", err)
+	}
+	if from := provider.calls[len(provider.calls)-1]; from != "20220825" {
+		t.Errorf("unexpect incremental from: %s", from)
+	}
+	if data.Length != full.Length || data.From != full.From || data.To != full.To {
+		t.Fatalf("unexpect merge result: length=%d from=%s to=%s", data.Length, data.From, data.To)
+	}
+	for i := range data.KLines {
+		if data.KLines[i] != full.KLines[i] {
+			t.Fatalf("node %d not match after merge", i)
+		}
+	}
+	last, err := cache.LastTimestamp("510500", PeriodDay, AdjustForward)
+	if err != nil || last != full.KLines[full.Length-1].Timestamp {
+		t.Errorf("unexpect last timestamp: %d err=%v", last, err)
+	}
+
+	// 历史数据被重新复权, 缓存失效后重新获取全部数据
+	adjusted := full
+	adjusted.KLines = append([]common.KLineNode(nil), full.KLines...)
+	for i := range adjusted.KLines {
+		adjusted.KLines[i].End -= 0.1
+	}
+	if _, err = MergeKLineData(data, adjusted); err == nil {
+		t.Fatalf("expect conflict error")
+	}
+	provider.data = adjusted
+	data, err = cache.Update("1.510500", PeriodDay, AdjustForward)
+	if err != nil {
+		t.Fatalf("update after conflict fail: err=%v", err)
+	}
+	if from := provider.calls[len(provider.calls)-1]; from != "" || data.KLines[0].End != adjusted.KLines[0].End {
+		t.Errorf("expect cache invalidated and refetched: from=%s", from)
+	}
+}
@@ -0,0 +1,28 @@
+package dao
+
+import (
+	"fmt"
+	"github.com/BlackCarDriver/GoProject-api/common/util"
+	"github.com/BlackCarDriver/StockMaster/common"
+)
+
+// CashYieldData 闲置现金收益率数据文件格式, 例如国债逆回购或货币基金的历史年化收益率
+type CashYieldData struct {
+	Name   string              `json:"name"`   // 收益率来源, 例如: GC001
+	Points []common.YieldPoint `json:"points"` // 年化收益率, 例如2%为0.02
+}
+
+// ReadCashYield 从指定文件中读取年化收益率序列, 按日期排序
+func ReadCashYield(path string) (series *common.YieldSeries, err error) {
+	var data CashYieldData
+	if err = util.UnmarshalJsonFromFile(path, &data); err != nil {
+		return
+	}
+	for _, point := range data.Points {
+		if _, err = ParseKLineTime(point.Day); err != nil || point.Rate < 0 {
+			err = fmt.Errorf("unexpect yield point: %+v", point)
+			return
+		}
+	}
+	return common.NewYieldSeries(data.Points), nil
+}
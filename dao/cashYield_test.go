@@ -0,0 +1,38 @@
+package dao
+
+import (
+	"github.com/BlackCarDriver/StockMaster/common"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadCashYield(t *testing.T) {
+	// 从文件读取收益率序列, 使用上一个计息日的收益率
+	path := filepath.Join(t.TempDir(), "gc001.json")
+	content := `{"name":"GC001","points":[{"day":"2022-06-02","rate":0.0365},{"day":"2022-06-01","rate":0}]}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("write fail: err=%v", err)
+	}
+	series, err := ReadCashYield(path)
+	if err != nil {
+		t.Fatalf("read yield fail: err=%v", err)
+	}
+	account := common.Account{Name: "Series", InitFundRMB: 100000, TargetStock: "600036",
+		Balance: common.BalanceInfo{BalanceRMB: 100000}, CashYield: series}
+	for _, desc := range []string{"2022-06-01", "2022-06-02", "2022-06-06"} {
+		ts, _ := ParseKLineTime(desc)
+		account.UpdateStat(common.KLineNode{Timestamp: ts, TimeDesc: desc, Start: 10, End: 10, Top: 10.5, Bottom: 9.5})
+	}
+	if math.Abs(account.TradStat.CashInterest-40) > 1e-6 {
+		t.Fatalf("unexpect series yield: stat=%+v", account.TradStat)
+	}
+
+	if err = os.WriteFile(path, []byte(`{"name":"GC001","points":[{"day":"2022/06/01","rate":0.02}]}`), 0644); err != nil {
+		t.Fatalf("write fail: err=%v", err)
+	}
+	if _, err = ReadCashYield(path); err == nil {
+		t.Fatalf("expect error on bad yield day")
+	}
+}
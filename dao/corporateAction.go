@@ -0,0 +1,89 @@
+package dao
+
+import (
+	"fmt"
+	"github.com/BlackCarDriver/GoProject-api/common/util"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// CorporateAction 除权除息事件 (现金分红, 送转股, 拆股合股)
+type CorporateAction struct {
+	ExDate string  `json:"exDate"` // 除权除息日, 格式: 2006-01-02
+	Cash   float64 `json:"cash"`   // 每股派息 (元)
+	Bonus  float64 `json:"bonus"`  // 每股送转股数, 例如10送3为0.3, 1拆2为1, 2合1为-0.5
+	Desc   string  `json:"desc"`   // 方案描述, 例如: 10派16.06元
+}
+
+// CorporateActionData 除权除息数据文件格式, 与k线数据放在同一目录, 命名为 {code}_actions.json
+type CorporateActionData struct {
+	Code    string            `json:"code"`
+	Name    string            `json:"name"`
+	Actions []CorporateAction `json:"actions"`
+}
+
+// RefPrice 根据上一日收盘价计算除权参考价
+func (c CorporateAction) RefPrice(prevClose float64) float64 {
+	return (prevClose - c.Cash) / (1 + c.Bonus)
+}
+
+// ReadCorporateActions 从指定文件中读取除权除息事件, 按除权日排序
+func ReadCorporateActions(path string) (actions []CorporateAction, err error) {
+	var data CorporateActionData
+	if err = util.UnmarshalJsonFromFile(path, &data); err != nil {
+		return
+	}
+	actions = data.Actions
+	for _, action := range actions {
+		if _, err = ParseKLineTime(action.ExDate); err != nil || action.Bonus <= -1 || action.Cash < 0 {
+			err = fmt.Errorf("unexpect corporate action: %+v", action)
+			return
+		}
+	}
+	sort.SliceStable(actions, func(i, j int) bool {
+		return actions[i].ExDate < actions[j].ExDate
+	})
+	return
+}
+
+// FetchCorporateActions 读取数据目录中指定股票的除权除息事件, 文件不存在时返回空列表
+func (f *FileProvider) FetchCorporateActions(secID string) (actions []CorporateAction, err error) {
+	_, code := SplitSecID(secID)
+	path := filepath.Join(f.Dir, fmt.Sprintf("%s_actions.json", code))
+	if _, err = os.Stat(path); os.IsNotExist(err) {
+		return nil, nil
+	}
+	return ReadCorporateActions(path)
+}
+
+// CalcActionFactors 根据除权除息事件和不复权数据计算复权因子
+func CalcActionFactors(raw KLineData, actions []CorporateAction) (factors []AdjustFactor, err error) {
+	if raw.Adjust != AdjustNone {
+		err = fmt.Errorf("expect unadjusted data but got %s", raw.Adjust)
+		return
+	}
+	if len(raw.KLines) == 0 {
+		return
+	}
+	factor, idx := 1.0, 0
+	firstDay := TradeDay(raw.KLines[0].TimeDesc)
+	for idx < len(actions) && actions[idx].ExDate <= firstDay { // 数据开始之前的事件不影响复权因子
+		idx++
+	}
+	for i := 1; i < len(raw.KLines) && idx < len(actions); i++ {
+		node, prevClose := raw.KLines[i], raw.KLines[i-1].End
+		day := TradeDay(node.TimeDesc)
+		applied := false
+		for ; idx < len(actions) && actions[idx].ExDate <= day; idx++ {
+			if refPrice := actions[idx].RefPrice(prevClose); refPrice > 0 {
+				factor *= prevClose / refPrice
+				prevClose, applied = refPrice, true
+			}
+		}
+		if applied {
+			factors = append(factors, AdjustFactor{Timestamp: node.Timestamp, TimeDesc: node.TimeDesc, Factor: factor})
+		}
+	}
+	return
+}
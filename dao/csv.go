@@ -0,0 +1,302 @@
+package dao
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/BlackCarDriver/StockMaster/calendar"
+	"github.com/BlackCarDriver/StockMaster/common"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/transform"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	EncodingUTF8 = "utf-8"
+	EncodingGBK  = "gbk"
+)
+
+// CSVColumns 各字段在csv中的列号 (从0开始), -1表示该字段不存在
+type CSVColumns struct {
+	Date      int // 日期 (可同时包含时间)
+	Time      int // 时间 (分钟线数据日期和时间分两列时使用)
+	Timestamp int // 时间戳 (不存在时按北京时间根据日期时间计算)
+	Start     int // 开盘价
+	End       int // 收盘价
+	Top       int // 最高
+	Bottom    int // 最低
+	Vol       int // 成交量
+	Vov       int // 成交额
+	Wave      int // 振幅
+	PriceWave int // 涨跌幅
+	PriceRise int // 涨跌额
+	HSL       int // 换手率
+}
+
+// CSVOption csv文件格式配置
+type CSVOption struct {
+	Code        string     // 股票代码 (csv中一般不包含, 读取时直接填入结果)
+	Name        string     // 股票名称
+	Comma       rune       // 分隔符, 默认为逗号
+	Encoding    string     // 文件编码, utf-8(默认) 或 gbk
+	HeaderRows  int        // 读取时跳过的表头行数, 写入时输出一行表头 (大于0时)
+	DateLayout  string     // 日期列格式, 为空时与TimeDesc格式一致 (2006-01-02 或 2006-01-02 15:04)
+	TimeLayout  string     // 时间列格式, 例如 1504 (仅Time列存在时使用)
+	SkipInvalid bool       // 跳过日期无法解析的行 (例如通达信导出文件末尾的数据来源说明)
+	Columns     CSVColumns // 列映射
+}
+
+var csvHeaders = []string{"日期", "时间", "时间戳", "开盘", "收盘", "最高", "最低", "成交量", "成交额", "振幅", "涨跌幅", "涨跌额", "换手率"}
+
+// DefaultCSVOption 与WriteKLineCSV默认输出格式一致的配置, 包含全部字段
+func DefaultCSVOption() CSVOption {
+	return CSVOption{
+		Comma:      ',',
+		Encoding:   EncodingUTF8,
+		HeaderRows: 1,
+		Columns: CSVColumns{Date: 0, Time: -1, Timestamp: 1, Start: 2, End: 3, Top: 4, Bottom: 5,
+			Vol: 6, Vov: 7, Wave: 8, PriceWave: 9, PriceRise: 10, HSL: 11},
+	}
+}
+
+// TDXCSVOption 通达信导出的日线数据格式: gbk编码, 制表符分隔, 两行表头, 列: 日期 开盘 最高 最低 收盘 成交量 成交额
+// 通达信导出的成交量以股为单位, 使用VolumeFill时Unit应设置为1
+func TDXCSVOption() CSVOption {
+	return CSVOption{
+		Comma:       '\t',
+		Encoding:    EncodingGBK,
+		HeaderRows:  2,
+		DateLayout:  "2006/01/02",
+		SkipInvalid: true,
+		Columns: CSVColumns{Date: 0, Time: -1, Timestamp: -1, Start: 1, Top: 2, Bottom: 3, End: 4,
+			Vol: 5, Vov: 6, Wave: -1, PriceWave: -1, PriceRise: -1, HSL: -1},
+	}
+}
+
+// 按照表头顺序获取各字段的列号及对应的节点字段
+func (c CSVColumns) fields(node *common.KLineNode) (columns []int, values []*float64) {
+	columns = []int{c.Start, c.End, c.Top, c.Bottom, c.Vol, c.Vov, c.Wave, c.PriceWave, c.PriceRise, c.HSL}
+	values = []*float64{&node.Start, &node.End, &node.Top, &node.Bottom, &node.Vol, &node.Vov, &node.Wave, &node.PriceWave, &node.PriceRise, &node.HSL}
+	return
+}
+
+func (c CSVColumns) width() (width int) {
+	for _, column := range []int{c.Date, c.Time, c.Timestamp, c.Start, c.End, c.Top, c.Bottom, c.Vol, c.Vov, c.Wave, c.PriceWave, c.PriceRise, c.HSL} {
+		if column+1 > width {
+			width = column + 1
+		}
+	}
+	return
+}
+
+// 日期时间列的解析格式, 为空表示按TimeDesc格式解析
+func (opt CSVOption) dateTimeLayout() string {
+	layout := opt.DateLayout
+	if layout == "" && opt.Columns.Time >= 0 {
+		layout = klineDayLayout
+	}
+	if opt.Columns.Time >= 0 {
+		layout += " " + opt.TimeLayout
+	}
+	return layout
+}
+
+// ReadKLineCSVFile 从csv文件读取k线数据
+func ReadKLineCSVFile(path string, opt CSVOption) (data KLineData, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	return ReadKLineCSV(file, opt)
+}
+
+// ReadKLineCSV 按照指定的列映射读取csv格式的k线数据
+func ReadKLineCSV(r io.Reader, opt CSVOption) (data KLineData, err error) {
+	if opt.Columns.Date < 0 {
+		err = fmt.Errorf("date column is required")
+		return
+	}
+	switch strings.ToLower(opt.Encoding) {
+	case "", EncodingUTF8:
+	case EncodingGBK:
+		r = transform.NewReader(r, simplifiedchinese.GBK.NewDecoder())
+	default:
+		err = fmt.Errorf("unsupported encoding: %s", opt.Encoding)
+		return
+	}
+	reader := csv.NewReader(r)
+	if opt.Comma != 0 {
+		reader.Comma = opt.Comma
+	}
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	layout := opt.dateTimeLayout()
+	data = KLineData{Code: opt.Code, Name: opt.Name, UpdateTime: time.Now().Unix()}
+	for line := 1; ; line++ {
+		record, readErr := reader.Read()
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			err = fmt.Errorf("read csv fail: line=%d err=%v", line, readErr)
+			return
+		}
+		if line <= opt.HeaderRows {
+			continue
+		}
+		node, rowErr := parseCSVRecord(record, opt, layout)
+		if rowErr != nil && opt.SkipInvalid {
+			log.Debug("skip invalid csv row: line=%d err=%v", line, rowErr)
+			continue
+		}
+		if rowErr != nil {
+			err = fmt.Errorf("parse csv fail: line=%d err=%v", line, rowErr)
+			return
+		}
+		data.KLines = append(data.KLines, node)
+	}
+	data.ResetSummary()
+	return
+}
+
+func parseCSVRecord(record []string, opt CSVOption, layout string) (node common.KLineNode, err error) {
+	get := func(column int) string {
+		if column < 0 || column >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[column])
+	}
+
+	desc := get(opt.Columns.Date)
+	if opt.Columns.Time >= 0 {
+		desc += " " + get(opt.Columns.Time)
+	}
+	if layout == "" { // 与TimeDesc格式一致
+		layout = klineDayLayout
+		if len(desc) > len(klineDayLayout) {
+			layout = klineMinLayout
+		}
+	}
+	t, err := time.ParseInLocation(layout, desc, calendar.Location)
+	if err != nil {
+		err = fmt.Errorf("unexpect time %q: %v", desc, err)
+		return
+	}
+	node.TimeDesc = t.Format(klineDayLayout)
+	if strings.Contains(layout, "15") { // 包含时间的分钟线数据
+		node.TimeDesc = t.Format(klineMinLayout)
+	}
+	node.Timestamp = t.Unix()
+	if value := get(opt.Columns.Timestamp); value != "" {
+		if node.Timestamp, err = strconv.ParseInt(value, 10, 64); err != nil {
+			err = fmt.Errorf("unexpect timestamp %q", value)
+			return
+		}
+	}
+
+	columns, values := opt.Columns.fields(&node)
+	for i, column := range columns {
+		value := strings.ReplaceAll(get(column), ",", "")
+		if value == "" || value == "-" {
+			continue
+		}
+		if *values[i], err = strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64); err != nil {
+			err = fmt.Errorf("unexpect %s %q", csvHeaders[i+3], value)
+			return
+		}
+	}
+	return
+}
+
+// WriteKLineCSVFile 将k线数据保存为csv文件
+func WriteKLineCSVFile(path string, data KLineData, opt CSVOption) (err error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	return WriteKLineCSV(file, data, opt)
+}
+
+// WriteKLineCSV 按照指定的列映射输出csv格式的k线数据, HeaderRows大于0时输出一行表头
+func WriteKLineCSV(w io.Writer, data KLineData, opt CSVOption) (err error) {
+	if opt.Columns.Date < 0 {
+		return fmt.Errorf("date column is required")
+	}
+	switch strings.ToLower(opt.Encoding) {
+	case "", EncodingUTF8:
+	case EncodingGBK:
+		encoder := transform.NewWriter(w, simplifiedchinese.GBK.NewEncoder())
+		defer func() {
+			if closeErr := encoder.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+		w = encoder
+	default:
+		return fmt.Errorf("unsupported encoding: %s", opt.Encoding)
+	}
+	writer := csv.NewWriter(w)
+	if opt.Comma != 0 {
+		writer.Comma = opt.Comma
+	}
+
+	width := opt.Columns.width()
+	if opt.HeaderRows > 0 {
+		header := make([]string, width)
+		var node common.KLineNode
+		columns, _ := opt.Columns.fields(&node)
+		columns = append([]int{opt.Columns.Date, opt.Columns.Time, opt.Columns.Timestamp}, columns...)
+		for i, column := range columns {
+			if column >= 0 {
+				header[column] = csvHeaders[i]
+			}
+		}
+		if err = writer.Write(header); err != nil {
+			return
+		}
+	}
+
+	dateLayout := opt.DateLayout
+	if dateLayout == "" && opt.Columns.Time >= 0 {
+		dateLayout = klineDayLayout
+	}
+	for _, node := range data.KLines {
+		layout := klineDayLayout
+		if len(node.TimeDesc) > len(klineDayLayout) {
+			layout = klineMinLayout
+		}
+		t, parseErr := time.Parse(layout, node.TimeDesc)
+		if parseErr != nil {
+			return fmt.Errorf("unexpect time desc: %q", node.TimeDesc)
+		}
+		row := make([]string, width)
+		row[opt.Columns.Date] = node.TimeDesc
+		if dateLayout != "" {
+			row[opt.Columns.Date] = t.Format(dateLayout)
+		}
+		if opt.Columns.Time >= 0 {
+			row[opt.Columns.Time] = t.Format(opt.TimeLayout)
+		}
+		if opt.Columns.Timestamp >= 0 {
+			row[opt.Columns.Timestamp] = strconv.FormatInt(node.Timestamp, 10)
+		}
+		columns, values := opt.Columns.fields(&node)
+		for i, column := range columns {
+			if column >= 0 {
+				row[column] = strconv.FormatFloat(*values[i], 'f', -1, 64)
+			}
+		}
+		if err = writer.Write(row); err != nil {
+			return
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,75 @@
+package dao
+
+import (
+	"bytes"
+	"github.com/BlackCarDriver/StockMaster/calendar"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestKLineCSVRoundTrip(t *testing.T) {
+	files, err := filepath.Glob("./mockdata/*_*.json")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("mock data not found: err=%v", err)
+	}
+	gbkOpt := DefaultCSVOption()
+	gbkOpt.Encoding, gbkOpt.Comma = EncodingGBK, ';'
+	for _, path := range files {
+		mock, err := ReadKLineMockData(path)
+		if err != nil {
+			t.Fatalf("read mock data fail: path=%s err=%v", path, err)
+		}
+		for _, opt := range []CSVOption{DefaultCSVOption(), gbkOpt} {
+			var buf bytes.Buffer
+			if err = WriteKLineCSV(&buf, mock, opt); err != nil {
+				t.Fatalf("write csv fail: path=%s err=%v", path, err)
+			}
+			opt.Code, opt.Name = mock.Code, mock.Name
+			data, err := ReadKLineCSV(&buf, opt)
+			if err != nil {
+				t.Fatalf("read csv fail: path=%s err=%v", path, err)
+			}
+			if !reflect.DeepEqual(data.KLines, mock.KLines) {
+				t.Fatalf("round trip mismatch: path=%s encoding=%s", path, opt.Encoding)
+			}
+			if data.Length != mock.Length || data.From != mock.From || data.To != mock.To || data.Name != mock.Name {
+				t.Fatalf("unexpect summary: path=%s length=%d from=%s to=%s", path, data.Length, data.From, data.To)
+			}
+		}
+	}
+}
+
+func TestReadTDXCSV(t *testing.T) {
+	content := "600036 招商银行 日线 前复权\n" +
+		"      日期\t    开盘\t    最高\t    最低\t    收盘\t    成交量\t    成交额\n" +
+		"2022/09/29\t33.80\t34.01\t33.32\t33.42\t48756300\t1636987904.00\n" +
+		"2022/09/30\t33.40\t33.95\t33.12\t33.65\t52370125\t1762114560.00\n" +
+		"数据来源:通达信\n"
+	encoded, err := simplifiedchinese.GBK.NewEncoder().Bytes([]byte(content))
+	if err != nil {
+		t.Fatalf("encode fail: err=%v", err)
+	}
+	opt := TDXCSVOption()
+	opt.Code, opt.Name = "600036", "招商银行"
+	data, err := ReadKLineCSV(bytes.NewReader(encoded), opt)
+	if err != nil {
+		t.Fatalf("read fail: err=%v", err)
+	}
+	if data.Length != 2 || data.From != "2022-09-29" || data.To != "2022-09-30" {
+		t.Fatalf("unexpect summary: %+v", data)
+	}
+	node := data.KLines[1]
+	if node.Start != 33.40 || node.Top != 33.95 || node.Bottom != 33.12 || node.End != 33.65 || node.Vol != 52370125 || node.Vov != 1762114560 {
+		t.Fatalf("unexpect node: %+v", node)
+	}
+	if day := calendar.InLocation(node.Timestamp).Format(klineDayLayout); day != "2022-09-30" {
+		t.Fatalf("unexpect timestamp: %d", node.Timestamp)
+	}
+
+	opt.SkipInvalid = false
+	if _, err = ReadKLineCSV(bytes.NewReader(encoded), opt); err == nil {
+		t.Fatalf("expect error on footer row")
+	}
+}
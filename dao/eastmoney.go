@@ -0,0 +1,114 @@
+package dao
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultKLineBaseURL = "http://push2his.eastmoney.com" // k线接口默认地址
+	eastMoneyUT         = "fa5fd1943c7b386f172d6893dbfba10b"
+	klinePath           = "/api/qt/stock/kline/get"
+	klineFields1        = "f1,f2,f3,f4,f5,f6"
+	klineFields2        = "f51,f52,f53,f54,f55,f56,f57,f58,f59,f60,f61"
+	klineDefaultEnd     = "20500101"
+	klineMaxLimit       = 1000000
+)
+
+// EastMoneyClient 通过东方财富http接口获取k线数据
+type EastMoneyClient struct {
+	BaseURL       string        // 接口地址, 测试时可替换为本地服务
+	Timeout       time.Duration // 单次请求超时时间
+	Retry         int           // 请求失败后的重试次数
+	RetryInterval time.Duration // 重试间隔
+	Limit         int           // 最多返回的节点数量 (lmt)
+}
+
+// NewEastMoneyClient 创建使用默认配置的客户端
+func NewEastMoneyClient() *EastMoneyClient {
+	return &EastMoneyClient{
+		BaseURL:       DefaultKLineBaseURL,
+		Timeout:       10 * time.Second,
+		Retry:         2,
+		RetryInterval: time.Second,
+		Limit:         klineMaxLimit,
+	}
+}
+
+func (c *EastMoneyClient) FetchKLine(secID string, period KLinePeriod, adjust AdjustMode, from, to string) (data KLineData, err error) {
+	if market, code := SplitSecID(secID); market == "" || code == "" {
+		err = fmt.Errorf("unexpect secID: %q", secID)
+		return
+	}
+	if adjust != AdjustNone && adjust != AdjustForward && adjust != AdjustBackward {
+		err = fmt.Errorf("unexpect adjust mode: %s", adjust)
+		return
+	}
+	body, err := httpGetWithRetry(c.KLineURL(secID, period, adjust, from, to), c.Timeout, c.Retry, c.RetryInterval)
+	if err != nil {
+		return
+	}
+	data, err = ParseKLineResp(body)
+	data.Adjust = adjust
+	return
+}
+
+// KLineURL 拼接k线接口的请求地址
+func (c *EastMoneyClient) KLineURL(secID string, period KLinePeriod, adjust AdjustMode, from, to string) string {
+	if from == "" {
+		from = "0"
+	}
+	if to == "" {
+		to = klineDefaultEnd
+	}
+	limit := c.Limit
+	if limit <= 0 {
+		limit = klineMaxLimit
+	}
+	params := url.Values{}
+	params.Set("secid", secID)
+	params.Set("ut", eastMoneyUT)
+	params.Set("fields1", klineFields1)
+	params.Set("fields2", klineFields2)
+	params.Set("klt", strconv.Itoa(int(period)))
+	params.Set("fqt", strconv.Itoa(adjust.FQT()))
+	params.Set("beg", from)
+	params.Set("end", to)
+	params.Set("lmt", strconv.Itoa(limit))
+	params.Set("_", strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10))
+	return strings.TrimRight(c.BaseURL, "/") + klinePath + "?" + params.Encode()
+}
+
+// 发送get请求, 网络错误或响应码非200时按照指定次数重试
+func httpGetWithRetry(rawURL string, timeout time.Duration, retry int, interval time.Duration) (body []byte, err error) {
+	client := &http.Client{Timeout: timeout}
+	for i := 0; i <= retry; i++ {
+		if i > 0 {
+			log.Warning("http get fail, retry later: times=%d err=%v", i, err)
+			time.Sleep(interval)
+		}
+		if body, err = httpGet(client, rawURL); err == nil {
+			return
+		}
+	}
+	err = fmt.Errorf("http get fail after %d retry: %v", retry, err)
+	return
+}
+
+func httpGet(client *http.Client, rawURL string) (body []byte, err error) {
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("unexpect status code: %d", resp.StatusCode)
+		return
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,51 @@
+package dao
+
+import (
+	"encoding/csv"
+	"github.com/BlackCarDriver/StockMaster/common"
+	"io"
+	"os"
+	"strconv"
+)
+
+var equityHeaders = []string{"时间戳", "时间", "价格", "现金", "持有份额", "持仓市值", "总资产", "持仓成本", "买入委托", "卖出委托"}
+
+// WriteEquityCSVFile 将资产曲线保存为csv文件
+func WriteEquityCSVFile(path string, points []common.EquityPoint) (err error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	return WriteEquityCSV(file, points)
+}
+
+// WriteEquityCSV 输出csv格式的资产曲线, 第一行为表头
+func WriteEquityCSV(w io.Writer, points []common.EquityPoint) (err error) {
+	writer := csv.NewWriter(w)
+	if err = writer.Write(equityHeaders); err != nil {
+		return
+	}
+	formatFloat := func(value float64) string {
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	for _, point := range points {
+		row := []string{
+			strconv.FormatInt(point.Timestamp, 10),
+			common.TimeFormat(point.Timestamp),
+			formatFloat(point.Price),
+			formatFloat(point.Cash),
+			strconv.Itoa(point.StockVol),
+			formatFloat(point.MarketValue),
+			formatFloat(point.TotalValue),
+			formatFloat(point.CostRMB),
+			strconv.Itoa(point.BuyEntrust),
+			strconv.Itoa(point.SellEntrust),
+		}
+		if err = writer.Write(row); err != nil {
+			return
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,27 @@
+package dao
+
+import (
+	"bytes"
+	"github.com/BlackCarDriver/StockMaster/common"
+	"strings"
+	"testing"
+)
+
+func TestWriteEquityCSV(t *testing.T) {
+	ts, _ := ParseKLineTime("2022-06-01")
+	points := []common.EquityPoint{
+		{Timestamp: ts, Price: 10, Cash: 90000, StockVol: 1000, MarketValue: 10000, TotalValue: 100000, CostRMB: 10000, SellEntrust: 1},
+		{Timestamp: ts + 86400, Price: 10.5, Cash: 90000, StockVol: 1000, MarketValue: 10500, TotalValue: 100500, CostRMB: 10000},
+	}
+	var buf bytes.Buffer
+	if err := WriteEquityCSV(&buf, points); err != nil {
+		t.Fatalf("export fail: err=%v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(points)+1 || lines[0] != strings.Join(equityHeaders, ",") {
+		t.Fatalf("unexpect csv lines: %v", lines)
+	}
+	if fields := strings.Split(lines[2], ","); fields[2] != "10.5" || fields[6] != "100500" || fields[9] != "0" {
+		t.Fatalf("unexpect csv row: %s", lines[2])
+	}
+}
@@ -0,0 +1,150 @@
+package dao
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/BlackCarDriver/StockMaster/calendar"
+	"github.com/BlackCarDriver/StockMaster/common"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	klineFieldNum  = 11                 // k线数据每行字段数 (f51~f61)
+	klineDayLayout = "2006-01-02"       // 日线及以上周期的时间格式
+	klineMinLayout = "2006-01-02 15:04" // 分钟线的时间格式
+)
+
+// KLineRowError 单行k线数据的解析错误
+type KLineRowError struct {
+	Index int    // 行号 (从0开始)
+	Row   string // 原始数据
+	Err   error
+}
+
+func (e KLineRowError) Error() string {
+	return fmt.Sprintf("row %d %q: %v", e.Index, e.Row, e.Err)
+}
+
+// ParseKLineError 解析k线响应时出现的格式错误行, 正常的行仍会被保留在返回结果中
+type ParseKLineError struct {
+	Rows []KLineRowError
+}
+
+func (e *ParseKLineError) Error() string {
+	if len(e.Rows) == 0 {
+		return "parse kline fail"
+	}
+	return fmt.Sprintf("parse kline fail: %d malformed rows, first: %v", len(e.Rows), e.Rows[0])
+}
+
+// TrimJsonp 去掉jsonp响应外层的回调包装, 例如: jQuery3510_1664948801885({...});
+func TrimJsonp(raw []byte) []byte {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || raw[0] == '{' || raw[0] == '[' {
+		return raw
+	}
+	begin, end := bytes.IndexByte(raw, '('), bytes.LastIndexByte(raw, ')')
+	if begin < 0 || end <= begin {
+		return raw
+	}
+	return bytes.TrimSpace(raw[begin+1 : end])
+}
+
+// ParseKLineResp 解析东方财富k线接口的原始响应, 得到k线图数据
+// 存在格式错误的行时, 返回的err为*ParseKLineError, data中只包含解析成功的节点
+func ParseKLineResp(raw []byte) (data KLineData, err error) {
+	var resp GetKLineResp
+	if err = json.Unmarshal(TrimJsonp(raw), &resp); err != nil {
+		err = fmt.Errorf("unmarshal kline resp fail: %v", err)
+		return
+	}
+	if resp.RC != 0 || resp.Data.Code == "" {
+		err = fmt.Errorf("unexpect kline resp: rc=%d code=%q", resp.RC, resp.Data.Code)
+		return
+	}
+	return ConvertKLineRespData(resp.Data)
+}
+
+// ConvertKLineRespData 将接口响应的data部分转换为k线图数据
+func ConvertKLineRespData(respData GetKLineRespData) (data KLineData, err error) {
+	data = KLineData{
+		Code:       respData.Code,
+		Name:       respData.Name,
+		UpdateTime: time.Now().Unix(),
+		KLines:     make([]common.KLineNode, 0, len(respData.KLine)),
+	}
+	var rowErrs []KLineRowError
+	for i, row := range respData.KLine {
+		node, rowErr := ParseKLineRow(row)
+		if rowErr != nil {
+			rowErrs = append(rowErrs, KLineRowError{Index: i, Row: row, Err: rowErr})
+			continue
+		}
+		data.KLines = append(data.KLines, node)
+	}
+	data.ResetSummary()
+	if len(rowErrs) > 0 {
+		err = &ParseKLineError{Rows: rowErrs}
+	}
+	return
+}
+
+// ParseKLineRow 解析一行逗号分隔的k线数据
+// 字段顺序与请求参数fields2一致: f51时间,f52开盘,f53收盘,f54最高,f55最低,f56成交量,f57成交额,f58振幅,f59涨跌幅,f60涨跌额,f61换手率
+func ParseKLineRow(row string) (node common.KLineNode, err error) {
+	fields := strings.Split(strings.TrimSpace(row), ",")
+	if len(fields) != klineFieldNum {
+		err = fmt.Errorf("expect %d fields but got %d", klineFieldNum, len(fields))
+		return
+	}
+	node.TimeDesc = strings.TrimSpace(fields[0])
+	if node.Timestamp, err = ParseKLineTime(node.TimeDesc); err != nil {
+		return
+	}
+	values := make([]float64, klineFieldNum-1)
+	for i, field := range fields[1:] {
+		if values[i], err = strconv.ParseFloat(strings.TrimSpace(field), 64); err != nil {
+			err = fmt.Errorf("parse field f%d fail: %v", 52+i, err)
+			return
+		}
+	}
+	node.Start, node.End, node.Top, node.Bottom = values[0], values[1], values[2], values[3]
+	node.Vol, node.Vov = values[4], values[5] // f56成交量(手), f57成交额(元)
+	node.Wave, node.PriceWave, node.PriceRise, node.HSL = values[6], values[7], values[8], values[9]
+	return
+}
+
+// ParseKLineTime 将k线的时间描述转换为时间戳 (按北京时间计算)
+func ParseKLineTime(desc string) (timestamp int64, err error) {
+	layout := klineDayLayout
+	if len(desc) > len(klineDayLayout) {
+		layout = klineMinLayout
+	}
+	t, err := time.ParseInLocation(layout, desc, calendar.Location)
+	if err != nil {
+		err = fmt.Errorf("unexpect time format: %q", desc)
+		return
+	}
+	return t.Unix(), nil
+}
+
+// TradeDay 获取k线时间描述中的日期部分, 例如 2022-09-30 15:00 返回 2022-09-30
+func TradeDay(timeDesc string) string {
+	if len(timeDesc) < len(klineDayLayout) {
+		return timeDesc
+	}
+	return timeDesc[:len(klineDayLayout)]
+}
+
+// ResetSummary 根据KLines重新计算节点数量和起止时间
+func (d *KLineData) ResetSummary() {
+	d.Length = len(d.KLines)
+	d.From, d.To = "", ""
+	if d.Length > 0 {
+		d.From = d.KLines[0].TimeDesc
+		d.To = d.KLines[d.Length-1].TimeDesc
+	}
+}
@@ -0,0 +1,56 @@
+package dao
+
+import (
+	"testing"
+)
+
+const klineRespSample = `jQuery35106032242962875369_1664948801885({"rc":0,"rt":17,"svr":181669437,"lt":1,"full":0,"dlmkts":"",
+"data":{"code":"600036","market":1,"name":"招商银行","decimal":2,"dktotal":4904,"preKPrice":34.47,"klines":[
+"2022-08-18 09:45,34.13,33.88,34.17,33.87,61664,209671995.00,0.88,-1.05,-0.36,0.03",
+"2022-08-18 10:15,33.82,33.77,33.87,33.75,37603,127077388.00,0.35,-0.18,-0.06,0.02",
+"2022-08-18 10:30,33.76,33.65,33.77,x,59025,198664574.00,0.62,-0.36,-0.12,0.03",
+"2022-08-18 10:30,33.76,33.65,33.77,33.56,59025,198664574.00,0.62,-0.36,-0.12,0.03"]}});`
+
+func TestParseKLineResp(t *testing.T) {
+	mock, err := ReadKLineMockData("./mockdata/600036_15min.json")
+	if err != nil {
+		t.Fatalf("read mock data fail: err=%v", err)
+	}
+
+	data, err := ParseKLineResp([]byte(klineRespSample))
+	parseErr, ok := err.(*ParseKLineError)
+	if !ok || len(parseErr.Rows) != 1 || parseErr.Rows[0].Index != 2 {
+		t.Fatalf("expect one malformed row at index 2: err=%v", err)
+	}
+	if data.Code != "600036" || data.Name != "招商银行" || data.Length != 3 || len(data.KLines) != 3 {
+		t.Fatalf("unexpect summary: %+v", data)
+	}
+	if data.From != "2022-08-18 09:45" || data.To != "2022-08-18 10:30" || data.UpdateTime == 0 {
+		t.Fatalf("unexpect time range: from=%s to=%s updateTime=%d", data.From, data.To, data.UpdateTime)
+	}
+	for i, node := range data.KLines {
+		if node.TimeDesc != mock.KLines[i].TimeDesc || node.Timestamp <= 0 {
+			t.Errorf("unexpect time: desc=%s timestamp=%d", node.TimeDesc, node.Timestamp)
+		}
+		if node != mock.KLines[i] {
+			t.Errorf("node %d not match mock data: \ngot=%+v \nexpect=%+v", i, node, mock.KLines[i])
+		}
+	}
+}
+
+func TestParseKLineTime(t *testing.T) {
+	cases := map[string]int64{
+		"2022-08-18 09:45": 1660787100,
+		"2022-09-30 15:00": 1664521200,
+		"2022-09-30":       1664467200,
+	}
+	for desc, expect := range cases {
+		timestamp, err := ParseKLineTime(desc)
+		if err != nil || timestamp != expect {
+			t.Errorf("unexpect timestamp: desc=%s got=%d expect=%d err=%v", desc, timestamp, expect, err)
+		}
+	}
+	if _, err := ParseKLineTime("2022/09/30"); err == nil {
+		t.Errorf("expect error for unknown format")
+	}
+}
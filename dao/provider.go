@@ -0,0 +1,124 @@
+package dao
+
+import (
+	"fmt"
+	"github.com/BlackCarDriver/StockMaster/common"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var log = common.GetLogger()
+
+// Provider k线数据来源
+type Provider interface {
+	// FetchKLine 获取指定股票的k线数据
+	// secID 格式为 市场.代码 (例如 1.600036), from/to 格式为 20060102, 为空时不限制
+	FetchKLine(secID string, period KLinePeriod, adjust AdjustMode, from, to string) (data KLineData, err error)
+}
+
+// String 周期名称, 同时用于模拟数据文件命名, 例如 600036_15min.json
+func (p KLinePeriod) String() string {
+	switch p {
+	case PeriodDay:
+		return "1day"
+	case PeriodWeek:
+		return "1week"
+	case PeriodMonth:
+		return "1month"
+	}
+	return fmt.Sprintf("%dmin", int(p))
+}
+
+// IsMinute 是否为分钟级别的周期
+func (p KLinePeriod) IsMinute() bool {
+	return p > 0 && p < PeriodDay
+}
+
+// String 复权方式名称
+func (m AdjustMode) String() string {
+	switch m {
+	case AdjustUnknown:
+		return "unknown"
+	case AdjustNone:
+		return "bfq"
+	case AdjustForward:
+		return "qfq"
+	case AdjustBackward:
+		return "hfq"
+	}
+	return fmt.Sprintf("adjust%d", int(m))
+}
+
+// FQT 复权方式对应的接口参数fqt: 0不复权, 1前复权, 2后复权
+func (m AdjustMode) FQT() int {
+	return int(m) - 1
+}
+
+// SplitSecID 将 1.600036 格式的secID拆分为市场和代码, 没有市场前缀时market为空
+func SplitSecID(secID string) (market, code string) {
+	if idx := strings.IndexByte(secID, '.'); idx >= 0 {
+		return secID[:idx], secID[idx+1:]
+	}
+	return "", secID
+}
+
+// FileProvider 从本地模拟数据目录读取k线数据
+// 文件命名: 前复权为 {code}_{period}.json, 其余为 {code}_{period}_{bfq|hfq}.json
+type FileProvider struct {
+	Dir string
+}
+
+// NewFileProvider 创建读取指定目录的数据源
+func NewFileProvider(dir string) *FileProvider {
+	return &FileProvider{Dir: dir}
+}
+
+// MockDataPath 获取指定数据对应的文件路径
+func (f *FileProvider) MockDataPath(code string, period KLinePeriod, adjust AdjustMode) string {
+	name := fmt.Sprintf("%s_%s.json", code, period)
+	if adjust != AdjustForward {
+		name = fmt.Sprintf("%s_%s_%s.json", code, period, adjust)
+	}
+	return filepath.Join(f.Dir, name)
+}
+
+func (f *FileProvider) FetchKLine(secID string, period KLinePeriod, adjust AdjustMode, from, to string) (data KLineData, err error) {
+	_, code := SplitSecID(secID)
+	path := f.MockDataPath(code, period, adjust)
+	if _, err = os.Stat(path); err != nil {
+		err = fmt.Errorf("mock data not found: secID=%s period=%s adjust=%s err=%v", secID, period, adjust, err)
+		return
+	}
+	if data, err = ReadKLineMockData(path); err != nil {
+		err = fmt.Errorf("read mock data fail: path=%s err=%v", path, err)
+		return
+	}
+	data.Adjust = adjust
+	return data.FilterByDate(from, to), nil
+}
+
+// ResetTimestamp 根据TimeDesc重新计算各节点的时间戳 (旧的模拟数据未按北京时间计算)
+func (d *KLineData) ResetTimestamp() (err error) {
+	for i := range d.KLines {
+		if d.KLines[i].Timestamp, err = ParseKLineTime(d.KLines[i].TimeDesc); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// FilterByDate 截取指定日期范围内的节点, from/to 格式为 20060102, 为空时不限制
+func (d KLineData) FilterByDate(from, to string) (result KLineData) {
+	result = d
+	result.KLines = nil
+	for _, node := range d.KLines {
+		day := dateParam(node.TimeDesc)
+		if (from != "" && day < from) || (to != "" && day > to) {
+			continue
+		}
+		result.KLines = append(result.KLines, node)
+	}
+	result.ResetSummary()
+	return
+}
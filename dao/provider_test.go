@@ -0,0 +1,87 @@
+package dao
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// 将模拟数据还原为k线接口的jsonp响应
+func mockKLineResp(data KLineData) []byte {
+	rows := make([]string, 0, len(data.KLines))
+	for _, n := range data.KLines {
+		rows = append(rows, fmt.Sprintf("%s,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v",
+			n.TimeDesc, n.Start, n.End, n.Top, n.Bottom, n.Vol, n.Vov, n.Wave, n.PriceWave, n.PriceRise, n.HSL))
+	}
+	resp := GetKLineResp{Data: GetKLineRespData{Code: data.Code, Name: data.Name, DKTotal: len(rows), KLine: rows}}
+	content, _ := json.Marshal(resp)
+	return []byte(fmt.Sprintf("jQuery35106032242962875369_1664948801885(%s);", content))
+}
+
+func TestFileProvider(t *testing.T) {
+	provider := NewFileProvider("./mockdata")
+	data, err := provider.FetchKLine("1.510500", PeriodDay, AdjustForward, "20220901", "20220930")
+	if err != nil {
+		t.Fatalf("fetch fail: err=%v", err)
+	}
+	if data.Length != 21 || data.From != "2022-09-01" || data.To != "2022-09-30" || data.Adjust != AdjustForward {
+		t.Fatalf("unexpect result: length=%d from=%s to=%s adjust=%s", data.Length, data.From, data.To, data.Adjust)
+	}
+	if raw, _ := ReadKLineMockData("./mockdata/510500_1day.json"); raw.Adjust != AdjustUnknown {
+		t.Fatalf("expect untagged mock data: adjust=%s", raw.Adjust)
+	}
+	if _, err = provider.FetchKLine("1.510500", PeriodDay, AdjustNone, "", ""); err == nil {
+		t.Fatalf("expect error when mock data not exist")
+	}
+}
+
+func TestEastMoneyClient(t *testing.T) {
+	mock, err := ReadKLineMockData("./mockdata/513050_15min.json")
+	if err != nil {
+		t.Fatalf("read mock data fail: err=%v", err)
+	}
+	requestTimes := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestTimes++
+		if requestTimes == 1 { // 第一次请求失败, 验证重试
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		q := r.URL.Query()
+		if r.URL.Path != klinePath || q.Get("secid") != "1.513050" || q.Get("klt") != "15" || q.Get("fqt") != "1" ||
+			q.Get("beg") != "0" || q.Get("end") != klineDefaultEnd || q.Get("lmt") != strconv.Itoa(klineMaxLimit) {
+			t.Errorf("unexpect request: %s", r.URL.String())
+		}
+		_, _ = w.Write(mockKLineResp(mock))
+	}))
+	defer server.Close()
+
+	client := NewEastMoneyClient()
+	client.BaseURL = server.URL
+	client.RetryInterval = 10 * time.Millisecond
+	data, err := client.FetchKLine("1.513050", Period15Min, AdjustForward, "", "")
+	if err != nil {
+		t.Fatalf("fetch fail: err=%v", err)
+	}
+	if requestTimes != 2 {
+		t.Errorf("expect 2 request but got %d", requestTimes)
+	}
+	if data.Code != mock.Code || data.Length != mock.Length || data.From != mock.From || data.To != mock.To {
+		t.Fatalf("unexpect result: code=%s length=%d from=%s to=%s", data.Code, data.Length, data.From, data.To)
+	}
+	for i := range data.KLines {
+		data.KLines[i].Timestamp = mock.KLines[i].Timestamp
+		if data.KLines[i] != mock.KLines[i] {
+			t.Fatalf("node %d not match: got=%+v expect=%+v", i, data.KLines[i], mock.KLines[i])
+		}
+	}
+
+	client.Retry = 0
+	if _, err = client.FetchKLine("513050", Period15Min, AdjustForward, "", ""); err == nil {
+		t.Fatalf("expect error for secID without market")
+	}
+}
@@ -0,0 +1,172 @@
+package dao
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultQuoteBaseURL = "https://push2.eastmoney.com" // 实时行情接口默认地址
+	quotePath           = "/api/qt/ulist.np/get"
+	quoteUT             = "b2884a393a59ad64002292a3e90d46a5"
+	quoteFields         = "f1,f2,f3,f4,f6,f12,f13,f104,f105,f106"
+)
+
+// Quote 实时行情快照
+type Quote struct {
+	SecID      string  `json:"secID"`      // 市场.代码, 例如 1.000001
+	Code       string  `json:"code"`       // 股票代码
+	Price      float64 `json:"price"`      // 现价
+	RiseRange  float64 `json:"riseRange"`  // 涨跌幅 (%)
+	RisePrice  float64 `json:"risePrice"`  // 涨跌额
+	TotalValue float64 `json:"totalValue"` // 总市值
+	UpCount    int     `json:"upCount"`    // 上涨数量 (仅指数有效)
+	DownCount  int     `json:"downCount"`  // 下跌数量 (仅指数有效)
+	FlatCount  int     `json:"flatCount"`  // 平盘数量 (仅指数有效)
+	Timestamp  int64   `json:"timestamp"`  // 获取时间
+}
+
+// MarketBreadth 市场涨跌家数统计
+type MarketBreadth struct {
+	Up   int `json:"up"`
+	Down int `json:"down"`
+	Flat int `json:"flat"`
+}
+
+// Breadth 获取指数的涨跌家数
+func (q Quote) Breadth() MarketBreadth {
+	return MarketBreadth{Up: q.UpCount, Down: q.DownCount, Flat: q.FlatCount}
+}
+
+// Total 统计的股票总数
+func (b MarketBreadth) Total() int {
+	return b.Up + b.Down + b.Flat
+}
+
+// UpRatio 上涨家数占比 (%)
+func (b MarketBreadth) UpRatio() float64 {
+	if b.Total() == 0 {
+		return 0
+	}
+	return float64(b.Up) * 100.0 / float64(b.Total())
+}
+
+// Add 合并多个市场的统计结果
+func (b MarketBreadth) Add(other MarketBreadth) MarketBreadth {
+	return MarketBreadth{Up: b.Up + other.Up, Down: b.Down + other.Down, Flat: b.Flat + other.Flat}
+}
+
+// QuoteClient 通过东方财富ulist接口批量获取实时行情
+type QuoteClient struct {
+	BaseURL       string        // 接口地址, 测试时可替换为本地服务
+	Timeout       time.Duration // 单次请求超时时间
+	Retry         int           // 请求失败后的重试次数
+	RetryInterval time.Duration // 重试间隔
+}
+
+// NewQuoteClient 创建使用默认配置的客户端
+func NewQuoteClient() *QuoteClient {
+	return &QuoteClient{
+		BaseURL:       DefaultQuoteBaseURL,
+		Timeout:       5 * time.Second,
+		Retry:         2,
+		RetryInterval: time.Second,
+	}
+}
+
+// FetchQuotes 一次请求获取多个股票或指数的行情, 返回结果按接口响应顺序排列
+func (c *QuoteClient) FetchQuotes(secIDs []string) (quotes []Quote, err error) {
+	if len(secIDs) == 0 {
+		err = fmt.Errorf("empty secIDs")
+		return
+	}
+	for _, secID := range secIDs {
+		if market, code := SplitSecID(secID); market == "" || code == "" {
+			err = fmt.Errorf("unexpect secID: %q", secID)
+			return
+		}
+	}
+	params := GetUListParams{
+		FLTT:      2,
+		SecIDS:    strings.Join(secIDs, ","),
+		Fields:    quoteFields,
+		UT:        quoteUT,
+		Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+	}
+	body, err := httpGetWithRetry(c.QuoteURL(params), c.Timeout, c.Retry, c.RetryInterval)
+	if err != nil {
+		return
+	}
+	return ParseUListResp(body, time.Now().Unix())
+}
+
+// FetchQuote 获取单个股票或指数的行情
+func (c *QuoteClient) FetchQuote(secID string) (quote Quote, err error) {
+	quotes, err := c.FetchQuotes([]string{secID})
+	if err != nil {
+		return
+	}
+	for _, item := range quotes {
+		if item.SecID == secID {
+			return item, nil
+		}
+	}
+	err = fmt.Errorf("quote not found: secID=%s", secID)
+	return
+}
+
+// FetchMarketBreadth 获取指数的涨跌家数, 例如 1.000001(上证指数) 0.399001(深证成指)
+func (c *QuoteClient) FetchMarketBreadth(indexSecIDs ...string) (breadth map[string]MarketBreadth, err error) {
+	quotes, err := c.FetchQuotes(indexSecIDs)
+	if err != nil {
+		return
+	}
+	breadth = make(map[string]MarketBreadth, len(quotes))
+	for _, quote := range quotes {
+		breadth[quote.SecID] = quote.Breadth()
+	}
+	return
+}
+
+// QuoteURL 拼接ulist接口的请求地址
+func (c *QuoteClient) QuoteURL(params GetUListParams) string {
+	values := url.Values{}
+	if params.CallBack != "" {
+		values.Set("cb", params.CallBack)
+	}
+	values.Set("fltt", strconv.Itoa(params.FLTT))
+	values.Set("secids", params.SecIDS)
+	values.Set("fields", params.Fields)
+	values.Set("ut", params.UT)
+	values.Set("_", strconv.FormatInt(params.Timestamp, 10))
+	return strings.TrimRight(c.BaseURL, "/") + quotePath + "?" + values.Encode()
+}
+
+// ParseUListResp 解析ulist接口的原始响应 (支持jsonp回调格式)
+func ParseUListResp(raw []byte, timestamp int64) (quotes []Quote, err error) {
+	var resp GetUListResp
+	if err = json.Unmarshal(TrimJsonp(raw), &resp); err != nil {
+		err = fmt.Errorf("unmarshal ulist resp fail: %v", err)
+		return
+	}
+	quotes = make([]Quote, 0, len(resp.Data.Diff))
+	for _, diff := range resp.Data.Diff {
+		quotes = append(quotes, Quote{
+			SecID:      fmt.Sprintf("%d.%s", diff.F13, diff.F12),
+			Code:       diff.F12,
+			Price:      diff.F2,
+			RiseRange:  diff.F3,
+			RisePrice:  diff.F4,
+			TotalValue: diff.F6,
+			UpCount:    diff.F104,
+			DownCount:  diff.F105,
+			FlatCount:  diff.F106,
+			Timestamp:  timestamp,
+		})
+	}
+	return
+}
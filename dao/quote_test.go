@@ -0,0 +1,52 @@
+package dao
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const ulistRespSample = `jQuery112305582875234802821_1665462176107({"rc":0,"rt":11,"svr":182482649,"lt":1,"full":1,"dlmkts":"",
+"data":{"total":2,"diff":[
+{"f1":2,"f2":2974.15,"f3":-1.49,"f4":-44.98,"f6":331508318208.0,"f12":"000001","f13":1,"f104":480,"f105":1667,"f106":58},
+{"f1":2,"f2":10587.25,"f3":-2.22,"f4":-240.23,"f6":449580703744.0,"f12":"399001","f13":0,"f104":647,"f105":2104,"f106":86}]}});`
+
+func TestQuoteClient(t *testing.T) {
+	var secIDs string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != quotePath || q.Get("fields") != quoteFields {
+			t.Errorf("unexpect request: %s", r.URL.String())
+		}
+		secIDs = q.Get("secids")
+		_, _ = w.Write([]byte(ulistRespSample))
+	}))
+	defer server.Close()
+
+	client := NewQuoteClient()
+	client.BaseURL = server.URL
+	quotes, err := client.FetchQuotes([]string{"1.000001", "0.399001"})
+	if err != nil {
+		t.Fatalf("fetch fail: err=%v", err)
+	}
+	if secIDs != "1.000001,0.399001" || len(quotes) != 2 {
+		t.Fatalf("unexpect result: secIDs=%s quotes=%d", secIDs, len(quotes))
+	}
+	sh := quotes[0]
+	if sh.SecID != "1.000001" || sh.Price != 2974.15 || sh.RiseRange != -1.49 || sh.RisePrice != -44.98 || sh.Timestamp == 0 {
+		t.Errorf("unexpect quote: %+v", sh)
+	}
+
+	breadth, err := client.FetchMarketBreadth("1.000001", "0.399001")
+	if err != nil {
+		t.Fatalf("fetch breadth fail: err=%v", err)
+	}
+	total := breadth["1.000001"].Add(breadth["0.399001"])
+	if total.Up != 1127 || total.Down != 3771 || total.Flat != 144 || total.Total() != 5042 {
+		t.Errorf("unexpect breadth: %+v", total)
+	}
+
+	if _, err = client.FetchQuote("1.600036"); err == nil {
+		t.Errorf("expect error when quote not in response")
+	}
+}
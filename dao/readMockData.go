@@ -1,9 +1,25 @@
 package dao
 
-import "github.com/BlackCarDriver/GoProject-api/common/util"
+import (
+	"encoding/json"
+	"github.com/BlackCarDriver/GoProject-api/common/util"
+	"os"
+)
 
-// ReadKLineMockData 从指定文件中读取模拟数据
+// ReadKLineMockData 从指定文件中读取模拟数据, 节点时间戳按TimeDesc重新计算为北京时间
 func ReadKLineMockData(path string) (mockData KLineData, err error) {
-	err = util.UnmarshalJsonFromFile(path, &mockData)
+	if err = util.UnmarshalJsonFromFile(path, &mockData); err != nil {
+		return
+	}
+	err = mockData.ResetTimestamp()
 	return
 }
+
+// WriteKLineMockData 将k线图数据保存为模拟数据文件, 格式与ReadKLineMockData一致
+func WriteKLineMockData(path string, data KLineData) (err error) {
+	content, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	return os.WriteFile(path, content, 0644)
+}
@@ -0,0 +1,165 @@
+package dao
+
+import (
+	"fmt"
+	"github.com/BlackCarDriver/StockMaster/calendar"
+	"github.com/BlackCarDriver/StockMaster/common"
+	"math"
+	"time"
+)
+
+const sessionMinutes = calendar.MorningClose - calendar.MorningOpen // 单个交易时段的分钟数
+
+// Resample 将k线数据聚合为更大的周期
+// 分钟线可聚合为更大的分钟线及日/周/月线, 日线只能聚合为周/月线, 节点时间为该周期最后一根k线的结束时间
+func Resample(data KLineData, target KLinePeriod) (result KLineData, err error) {
+	result = data
+	result.KLines = nil
+	if len(data.KLines) == 0 {
+		result.ResetSummary()
+		return
+	}
+	isMinuteSrc := len(data.KLines[0].TimeDesc) > len(klineDayLayout)
+	if target.IsMinute() {
+		if !isMinuteSrc {
+			err = fmt.Errorf("can not resample daily data to %s", target)
+			return
+		}
+		if err = checkMinuteStep(data.KLines, target); err != nil {
+			return
+		}
+	}
+	if target != PeriodDay && target != PeriodWeek && target != PeriodMonth && !target.IsMinute() {
+		err = fmt.Errorf("unexpect target period: %d", target)
+		return
+	}
+	if target == PeriodDay && !isMinuteSrc {
+		err = fmt.Errorf("source data is already daily")
+		return
+	}
+
+	var group []common.KLineNode
+	var groupKey string
+	prevClose := 0.0 // 第一个聚合节点之前没有收盘价, 涨跌数据留空
+	for _, node := range data.KLines {
+		key, keyErr := resampleKey(node.TimeDesc, target)
+		if keyErr != nil {
+			err = keyErr
+			return
+		}
+		if len(group) > 0 && key != groupKey {
+			merged := mergeKLineNodes(group, groupKey, target, prevClose)
+			result.KLines = append(result.KLines, merged)
+			prevClose = merged.End
+			group = group[:0]
+		}
+		groupKey = key
+		group = append(group, node)
+	}
+	merged := mergeKLineNodes(group, groupKey, target, prevClose)
+	result.KLines = append(result.KLines, merged)
+	for i := range result.KLines {
+		if result.KLines[i].Timestamp, err = ParseKLineTime(result.KLines[i].TimeDesc); err != nil {
+			return
+		}
+	}
+	result.ResetSummary()
+	return
+}
+
+// 计算节点所属的聚合分组, 分钟线返回所属时段的结束时间, 日/周/月线返回日期分组
+func resampleKey(timeDesc string, target KLinePeriod) (key string, err error) {
+	layout := klineDayLayout
+	if len(timeDesc) > len(klineDayLayout) {
+		layout = klineMinLayout
+	}
+	t, err := time.Parse(layout, timeDesc)
+	if err != nil {
+		err = fmt.Errorf("unexpect time format: %q", timeDesc)
+		return
+	}
+	switch target {
+	case PeriodDay:
+		return t.Format(klineDayLayout), nil
+	case PeriodWeek:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week), nil
+	case PeriodMonth:
+		return t.Format("2006-01"), nil
+	}
+
+	offset, err := sessionOffset(t.Hour()*60 + t.Minute())
+	if err != nil {
+		err = fmt.Errorf("%s: %v", timeDesc, err)
+		return
+	}
+	step := int(target)
+	end := (offset + step - 1) / step * step
+	if end == 0 { // 09:30的集合竞价节点归入第一个时段
+		end = step
+	}
+	return fmt.Sprintf("%s %s", t.Format(klineDayLayout), sessionClock(end)), nil
+}
+
+// 将一天中的时间(分钟)转换为从开盘起计算的交易分钟数
+func sessionOffset(clock int) (offset int, err error) {
+	switch {
+	case clock >= calendar.MorningOpen && clock <= calendar.MorningClose:
+		return clock - calendar.MorningOpen, nil
+	case clock > calendar.AfternoonOpen && clock <= calendar.AfternoonClose:
+		return sessionMinutes + clock - calendar.AfternoonOpen, nil
+	}
+	return 0, fmt.Errorf("time out of trading session")
+}
+
+// 将交易分钟数转换为 15:04 格式的时间
+func sessionClock(offset int) string {
+	clock := calendar.MorningOpen + offset
+	if offset > sessionMinutes {
+		clock = calendar.AfternoonOpen + offset - sessionMinutes
+	}
+	return fmt.Sprintf("%02d:%02d", clock/60, clock%60)
+}
+
+// 检查目标周期是否为原数据周期的整数倍
+func checkMinuteStep(nodes []common.KLineNode, target KLinePeriod) (err error) {
+	step := inferMinuteStep(nodes)
+	if step > 0 && (int(target) < step || int(target)%step != 0) {
+		return fmt.Errorf("can not resample %dmin data to %s", step, target)
+	}
+	return
+}
+
+// 合并同一分组内的节点, 涨跌幅等数据按照上一个聚合节点的收盘价重新计算, prevClose为0时不计算
+func mergeKLineNodes(group []common.KLineNode, key string, target KLinePeriod, prevClose float64) (merged common.KLineNode) {
+	first, last := group[0], group[len(group)-1]
+	merged = common.KLineNode{
+		TimeDesc: key,
+		Start:    first.Start,
+		End:      last.End,
+		Top:      first.Top,
+		Bottom:   first.Bottom,
+	}
+	if target == PeriodWeek || target == PeriodMonth {
+		merged.TimeDesc = last.TimeDesc[:len(klineDayLayout)]
+	}
+	for _, node := range group {
+		merged.Top = math.Max(merged.Top, node.Top)
+		merged.Bottom = math.Min(merged.Bottom, node.Bottom)
+		merged.Vol += node.Vol
+		merged.Vov += node.Vov
+		merged.HSL += node.HSL
+	}
+	if prevClose != 0 {
+		merged.Wave = roundTo((merged.Top-merged.Bottom)/math.Abs(prevClose)*100, 2)
+		merged.PriceWave = roundTo(common.CountRiseRange(prevClose, merged.End), 2)
+		merged.PriceRise = roundTo(merged.End-prevClose, 3)
+	}
+	merged.HSL = roundTo(merged.HSL, 2)
+	return
+}
+
+func roundTo(value float64, decimal int) float64 {
+	base := math.Pow10(decimal)
+	return math.Round(value*base) / base
+}
@@ -0,0 +1,65 @@
+package dao
+
+import (
+	"math"
+	"testing"
+)
+
+func TestResample(t *testing.T) {
+	provider := NewFileProvider("./mockdata")
+	minute, err := provider.FetchKLine("1.510500", Period15Min, AdjustForward, "", "")
+	if err != nil {
+		t.Fatalf("read mock data fail: err=%v", err)
+	}
+	daily, err := provider.FetchKLine("1.510500", PeriodDay, AdjustForward, "20220818", "20220930")
+	if err != nil {
+		t.Fatalf("read mock data fail: err=%v", err)
+	}
+
+	// 15分钟线聚合为日线, 与日线数据对比
+	result, err := Resample(minute, PeriodDay)
+	if err != nil {
+		t.Fatalf("resample fail: err=%v", err)
+	}
+	if result.Length != daily.Length || result.From != daily.From || result.To != daily.To {
+		t.Fatalf("unexpect summary: length=%d from=%s to=%s", result.Length, result.From, result.To)
+	}
+	for i, node := range result.KLines {
+		expect := daily.KLines[i]
+		if node.Timestamp != expect.Timestamp || node.End != expect.End || node.Top != expect.Top || node.Bottom != expect.Bottom {
+			t.Errorf("node %s not match daily data: got=%+v expect=%+v", node.TimeDesc, node, expect)
+		}
+		if i == 0 && (node.PriceRise != 0 || node.PriceWave != 0 || node.Wave != 0) {
+			t.Errorf("expect empty rise data on first node: got=%+v", node)
+		}
+		if i > 0 && math.Abs(node.PriceRise-expect.PriceRise) > 0.0011 {
+			t.Errorf("unexpect price rise %s: got=%v expect=%v", node.TimeDesc, node.PriceRise, expect.PriceRise)
+		}
+	}
+
+	// 按交易时段聚合分钟线
+	result, err = Resample(minute, Period60Min)
+	if err != nil {
+		t.Fatalf("resample fail: err=%v", err)
+	}
+	if result.Length != daily.Length*4 {
+		t.Fatalf("unexpect 60min length: %d", result.Length)
+	}
+	for i, clock := range []string{"10:30", "11:30", "14:00", "15:00"} {
+		if desc := result.KLines[i].TimeDesc; desc != "2022-08-18 "+clock {
+			t.Errorf("unexpect 60min time: %s", desc)
+		}
+	}
+	if _, err = Resample(result, Period30Min); err == nil {
+		t.Errorf("expect error when target period smaller than source")
+	}
+
+	// 日线聚合为周线
+	result, err = Resample(daily, PeriodWeek)
+	if err != nil {
+		t.Fatalf("resample fail: err=%v", err)
+	}
+	if result.Length != 7 || result.KLines[0].TimeDesc != "2022-08-19" || result.To != "2022-09-30" {
+		t.Fatalf("unexpect week result: length=%d first=%s to=%s", result.Length, result.KLines[0].TimeDesc, result.To)
+	}
+}
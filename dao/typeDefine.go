@@ -22,7 +22,7 @@ type GetKLineResp struct {
 	RC     int              `json:"rc"`
 	RT     int              `json:"rt"`
 	SVR    int64            `json:"svr"`
-	LT     string           `json:"lt"`
+	LT     int              `json:"lt"`
 	Full   int              `json:"full"`
 	DlMkts string           `json:"dlmkts"`
 	Data   GetKLineRespData `json:"data"`
@@ -69,6 +69,7 @@ type GetUListRespDataDiff struct {
 	F4   float64 `json:"f4"`   // 增值
 	F6   float64 `json:"f6"`   // 总市值
 	F12  string  `json:"f12"`  // 股票代码
+	F13  int     `json:"f13"`  // 市场 (1-沪市 0-深市)
 	F104 int     `json:"f104"` // 涨_数量
 	F105 int     `json:"f105"` // 跌_数量
 	F106 int     `json:"f106"` // 平_数量
@@ -83,5 +84,29 @@ type KLineData struct {
 	Length     int                `json:"length"`     // k线图节点数量
 	From       string             `json:"from"`       // 开始时间
 	To         string             `json:"to"`         // 结束时间
+	Adjust     AdjustMode         `json:"adjust"`     // 复权方式
 	KLines     []common.KLineNode `json:"kLines"`
 }
+
+// ========================== 数据源参数  =======================
+
+// KLinePeriod k线周期, 取值与接口参数klt一致
+type KLinePeriod int
+
+// AdjustMode 复权方式, 零值表示数据没有标记复权方式 (例如旧的模拟数据), 接口参数fqt见FQT
+type AdjustMode int
+
+const (
+	Period1Min     KLinePeriod = 1
+	Period5Min     KLinePeriod = 5
+	Period15Min    KLinePeriod = 15
+	Period30Min    KLinePeriod = 30
+	Period60Min    KLinePeriod = 60
+	PeriodDay      KLinePeriod = 101
+	PeriodWeek     KLinePeriod = 102
+	PeriodMonth    KLinePeriod = 103
+	AdjustUnknown  AdjustMode  = 0 // 未知, 按复权数据处理
+	AdjustNone     AdjustMode  = 1 // 不复权
+	AdjustForward  AdjustMode  = 2 // 前复权
+	AdjustBackward AdjustMode  = 3 // 后复权
+)
@@ -0,0 +1,198 @@
+package dao
+
+import (
+	"fmt"
+	"github.com/BlackCarDriver/StockMaster/common"
+	"sort"
+	"strings"
+	"time"
+)
+
+type IssueType string // 数据问题类型
+
+const (
+	IssueNonPositivePrice IssueType = "价格非正数"
+	IssueHighBelowLow     IssueType = "最高价低于最低价"
+	IssuePriceOutOfRange  IssueType = "开盘或收盘价超出最高最低价范围"
+	IssueTimeDisorder     IssueType = "时间戳非递增"
+	IssueTimeDuplicate    IssueType = "时间戳重复"
+	IssueMissingDay       IssueType = "缺少交易日"
+	IssueMissingBar       IssueType = "缺少分钟k线"
+	IssueSummaryMismatch  IssueType = "概述信息与节点不一致"
+)
+
+// ValidateIssue 数据校验发现的单个问题
+type ValidateIssue struct {
+	Type     IssueType `json:"type"`
+	Index    int       `json:"index"`    // 节点下标 (-1表示非节点问题)
+	TimeDesc string    `json:"timeDesc"` // 问题节点或缺失日期的时间
+	Desc     string    `json:"desc"`     // 具体描述
+}
+
+// ValidateReport k线数据校验报告
+type ValidateReport struct {
+	Code   string          `json:"code"`
+	Total  int             `json:"total"` // 节点数量
+	Issues []ValidateIssue `json:"issues"`
+}
+
+// ValidateOption 数据校验配置
+type ValidateOption struct {
+	IsTradingDay func(day time.Time) bool // 交易日历, 为空时不检查缺失的交易日
+}
+
+// IsValid 是否没有发现任何问题
+func (r ValidateReport) IsValid() bool {
+	return len(r.Issues) == 0
+}
+
+// Count 统计各类问题的数量
+func (r ValidateReport) Count() map[IssueType]int {
+	counter := make(map[IssueType]int)
+	for _, issue := range r.Issues {
+		counter[issue.Type]++
+	}
+	return counter
+}
+
+// Summary 问题概述, 例如: 价格非正数=1140 缺少交易日=2
+func (r ValidateReport) Summary() string {
+	if r.IsValid() {
+		return "无异常"
+	}
+	counter := r.Count()
+	types := make([]string, 0, len(counter))
+	for issueType := range counter {
+		types = append(types, string(issueType))
+	}
+	sort.Strings(types)
+	items := make([]string, 0, len(types))
+	for _, issueType := range types {
+		items = append(items, fmt.Sprintf("%s=%d", issueType, counter[IssueType(issueType)]))
+	}
+	return strings.Join(items, " ")
+}
+
+// ValidateKLineData 检查k线数据中的异常价格, 时间顺序, 缺失数据以及概述信息是否一致
+func ValidateKLineData(data KLineData, opt ValidateOption) (report ValidateReport) {
+	report = ValidateReport{Code: data.Code, Total: len(data.KLines)}
+	add := func(issueType IssueType, index int, timeDesc string, format string, args ...interface{}) {
+		report.Issues = append(report.Issues, ValidateIssue{
+			Type:     issueType,
+			Index:    index,
+			TimeDesc: timeDesc,
+			Desc:     fmt.Sprintf(format, args...),
+		})
+	}
+
+	// 概述信息
+	if data.Length != len(data.KLines) {
+		add(IssueSummaryMismatch, -1, "", "length=%d 实际节点数量=%d", data.Length, len(data.KLines))
+	}
+	if n := len(data.KLines); n > 0 {
+		if data.From != data.KLines[0].TimeDesc {
+			add(IssueSummaryMismatch, -1, "", "from=%s 第一个节点=%s", data.From, data.KLines[0].TimeDesc)
+		}
+		if data.To != data.KLines[n-1].TimeDesc {
+			add(IssueSummaryMismatch, -1, "", "to=%s 最后一个节点=%s", data.To, data.KLines[n-1].TimeDesc)
+		}
+	}
+
+	// 单个节点的价格及时间顺序
+	for i, node := range data.KLines {
+		if node.Start <= 0 || node.End <= 0 || node.Top <= 0 || node.Bottom <= 0 {
+			add(IssueNonPositivePrice, i, node.TimeDesc, "开盘=%v 收盘=%v 最高=%v 最低=%v", node.Start, node.End, node.Top, node.Bottom)
+		}
+		if node.Top < node.Bottom {
+			add(IssueHighBelowLow, i, node.TimeDesc, "最高=%v 最低=%v", node.Top, node.Bottom)
+		} else if !inRange(node.Start, node.Bottom, node.Top) || !inRange(node.End, node.Bottom, node.Top) {
+			add(IssuePriceOutOfRange, i, node.TimeDesc, "开盘=%v 收盘=%v 区间=[%v~%v]", node.Start, node.End, node.Bottom, node.Top)
+		}
+		if i == 0 {
+			continue
+		}
+		prev := data.KLines[i-1]
+		if node.Timestamp == prev.Timestamp {
+			add(IssueTimeDuplicate, i, node.TimeDesc, "与上一个节点时间戳相同: %d", node.Timestamp)
+		} else if node.Timestamp < prev.Timestamp {
+			add(IssueTimeDisorder, i, node.TimeDesc, "早于上一个节点: %s", prev.TimeDesc)
+		}
+	}
+
+	checkMissingBars(data.KLines, add)
+	if opt.IsTradingDay != nil {
+		checkMissingDays(data.KLines, opt.IsTradingDay, add)
+	}
+	return
+}
+
+func inRange(value, low, high float64) bool {
+	return value >= low && value <= high
+}
+
+// 检查分钟线每个交易日的节点数量是否完整
+func checkMissingBars(nodes []common.KLineNode, add func(IssueType, int, string, string, ...interface{})) {
+	if len(nodes) == 0 || len(nodes[0].TimeDesc) <= len(klineDayLayout) {
+		return
+	}
+	step := inferMinuteStep(nodes)
+	if step <= 0 {
+		return
+	}
+	expect := 2 * sessionMinutes / step
+	count, first := 0, 0
+	for i := range nodes {
+		count++
+		isDayEnd := i == len(nodes)-1 || nodes[i+1].TimeDesc[:len(klineDayLayout)] != nodes[i].TimeDesc[:len(klineDayLayout)]
+		if !isDayEnd {
+			continue
+		}
+		// 数据首尾的交易日可能不完整, 只检查中间的交易日
+		if count < expect && first > 0 && i < len(nodes)-1 {
+			add(IssueMissingBar, first, nodes[i].TimeDesc[:len(klineDayLayout)], "%dmin节点数量=%d 应有=%d", step, count, expect)
+		}
+		count, first = 0, i+1
+	}
+}
+
+// 根据交易日历检查数据时间范围内缺失的交易日
+func checkMissingDays(nodes []common.KLineNode, isTradingDay func(time.Time) bool, add func(IssueType, int, string, string, ...interface{})) {
+	days := make(map[string]bool)
+	for _, node := range nodes {
+		if len(node.TimeDesc) >= len(klineDayLayout) {
+			days[node.TimeDesc[:len(klineDayLayout)]] = true
+		}
+	}
+	if len(nodes) == 0 {
+		return
+	}
+	begin, err1 := time.Parse(klineDayLayout, nodes[0].TimeDesc[:len(klineDayLayout)])
+	end, err2 := time.Parse(klineDayLayout, nodes[len(nodes)-1].TimeDesc[:len(klineDayLayout)])
+	if err1 != nil || err2 != nil {
+		return
+	}
+	for day := begin; !day.After(end); day = day.AddDate(0, 0, 1) {
+		desc := day.Format(klineDayLayout)
+		if !days[desc] && isTradingDay(day) {
+			add(IssueMissingDay, -1, desc, "交易日 %s 没有数据", desc)
+		}
+	}
+}
+
+// 推算分钟线的周期 (同一交易日内相邻节点的最小间隔)
+func inferMinuteStep(nodes []common.KLineNode) (step int) {
+	for i := 1; i < len(nodes); i++ {
+		prev, cur := nodes[i-1].TimeDesc, nodes[i].TimeDesc
+		if len(prev) <= len(klineDayLayout) || len(cur) <= len(klineDayLayout) || prev[:len(klineDayLayout)] != cur[:len(klineDayLayout)] {
+			continue
+		}
+		prevTime, _ := time.Parse(klineMinLayout, prev)
+		curTime, _ := time.Parse(klineMinLayout, cur)
+		prevOffset, _ := sessionOffset(prevTime.Hour()*60 + prevTime.Minute())
+		curOffset, _ := sessionOffset(curTime.Hour()*60 + curTime.Minute())
+		if diff := curOffset - prevOffset; diff > 0 && (step == 0 || diff < step) {
+			step = diff
+		}
+	}
+	return
+}
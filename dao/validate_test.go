@@ -0,0 +1,51 @@
+package dao
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidateKLineData(t *testing.T) {
+	data, err := ReadKLineMockData("./mockdata/600036_1day.json")
+	if err != nil {
+		t.Fatalf("read mock data fail: err=%v", err)
+	}
+	report := ValidateKLineData(data, ValidateOption{})
+	if report.IsValid() || report.Count()[IssueNonPositivePrice] != 1140 {
+		t.Fatalf("expect non-positive price issues: %s", report.Summary())
+	}
+
+	data, err = ReadKLineMockData("./mockdata/510500_15min.json")
+	if err != nil {
+		t.Fatalf("read mock data fail: err=%v", err)
+	}
+	if report = ValidateKLineData(data, ValidateOption{}); !report.IsValid() {
+		t.Fatalf("expect valid data: %s", report.Summary())
+	}
+
+	// 构造各类异常
+	data.KLines = append(data.KLines[:0:0], data.KLines[:48]...)
+	data.KLines[1].Top, data.KLines[1].Bottom = data.KLines[1].Bottom, data.KLines[1].Top
+	data.KLines[2].Start = data.KLines[2].Top + 1
+	data.KLines[3].Timestamp = data.KLines[2].Timestamp
+	data.KLines = append(data.KLines[:20], data.KLines[21:]...) // 第二个交易日缺少一个节点
+	isWeekday := func(day time.Time) bool {
+		return day.Weekday() != time.Saturday && day.Weekday() != time.Sunday
+	}
+	report = ValidateKLineData(data, ValidateOption{IsTradingDay: isWeekday})
+	counter := report.Count()
+	expect := map[IssueType]int{
+		IssueHighBelowLow:    1,
+		IssuePriceOutOfRange: 1,
+		IssueTimeDuplicate:   1,
+		IssueTimeDisorder:    0,
+		IssueMissingBar:      1,
+		IssueMissingDay:      0,
+		IssueSummaryMismatch: 2,
+	}
+	for issueType, n := range expect {
+		if counter[issueType] != n {
+			t.Errorf("unexpect %s count: got=%d expect=%d", issueType, counter[issueType], n)
+		}
+	}
+}
@@ -5,6 +5,7 @@ go 1.16
 require (
 	github.com/BlackCarDriver/GoProject-api v1.0.2
 	github.com/astaxie/beego v1.12.3
+	golang.org/x/text v0.3.6
 )
 
 replace github.com/BlackCarDriver/GoProject-api => ./../api
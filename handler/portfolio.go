@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/BlackCarDriver/GoProject-api/color"
+	"github.com/BlackCarDriver/StockMaster/common"
+	"github.com/BlackCarDriver/StockMaster/dao"
+	"github.com/BlackCarDriver/StockMaster/strategy"
+	"sort"
+)
+
+// 组合模拟中的一个节点
+type portfolioBar struct {
+	symbol string
+	moment common.KLineNode
+}
+
+// SimulatePortfolio 按时间顺序合并各品种的k线数据, 依次更新对应子账号的最新价格并执行组合策略, 模拟过程直接修改portfolio
+// 每份k线数据的Code需要与组合中某个子账号的TargetStock相同, 时间相同的节点按stockData的顺序执行
+// 复权因子和除权除息事件只对单个品种有效, 组合模拟暂不支持; 组合没有快照, 也不支持从快照继续模拟
+func SimulatePortfolio(portfolio *common.Portfolio, stockData []dao.KLineData, strategy strategy.PortfolioStrategy, opt SimulateOption) (err error) {
+	if portfolio == nil || portfolio.InitFundRMB <= 0.0 || portfolio.Name == "" || len(stockData) == 0 {
+		return fmt.Errorf("unexpect params")
+	}
+	if len(opt.AdjustFactors) > 0 || len(opt.ExRights) > 0 {
+		return fmt.Errorf("unexpect params: AdjustFactors and ExRights are not supported by portfolio")
+	}
+	if opt.Resume {
+		return fmt.Errorf("unexpect params: Resume is not supported by portfolio")
+	}
+	if opt.Sampling != common.SampleNone {
+		portfolio.Sampling = opt.Sampling
+	}
+	var bars []portfolioBar
+	for _, data := range stockData {
+		account, isExist := portfolio.Account(data.Code)
+		if !isExist {
+			return fmt.Errorf("unexpect params: no account for kline data, code=%s", data.Code)
+		}
+		if account.TargetName == "" { // 涨跌幅限制需要根据名称判断是否为ST股票
+			account.TargetName = data.Name
+		}
+		if data, err = toTradData(data, nil); err != nil {
+			return
+		}
+		if err = validateKLine(data, opt); err != nil {
+			return
+		}
+		for _, moment := range data.KLines {
+			bars = append(bars, portfolioBar{symbol: data.Code, moment: moment})
+		}
+	}
+	sort.SliceStable(bars, func(i, j int) bool {
+		return bars[i].moment.Timestamp < bars[j].moment.Timestamp
+	})
+
+	for i, bar := range bars {
+		if err = portfolio.UpdateStat(bar.symbol, bar.moment); err != nil {
+			return
+		}
+		if err = strategy.Execute(portfolio, bar.symbol, bar.moment); err != nil {
+			log.Error("execute fail: i=%d symbol=%s err=%v moment=%+v", i, bar.symbol, err, bar.moment)
+			return
+		}
+		portfolio.RecordEquity(bar.moment.Timestamp)
+	}
+	return
+}
+
+// PrintPortfolioResult 在控制台打印组合模拟结果
+func PrintPortfolioResult(portfolio *common.Portfolio, strategy strategy.PortfolioStrategy) {
+	if portfolio == nil {
+		log.Warning("unexpect nil portfolio")
+		return
+	}
+	t := portfolio.TradStat
+	total := portfolio.TotalValue()
+
+	color.Blue("============ 策略描述 =============")
+	color.HiBlack(strategy.GetDesc())
+
+	color.Blue("============ 组合信息 =============")
+	color.HiBlack("组合名称: %s", portfolio.Name)
+	color.HiBlack("备注信息: %s", portfolio.Note)
+	color.HiBlack("初始金额: %.2f", portfolio.InitFundRMB)
+
+	color.Blue("============ 操作日志 =============")
+	for _, item := range portfolio.ActionLog {
+		color.HiBlack("%s, %s, %s", common.TimeFormat(item.Timestamp), item.Mode, item.Desc)
+	}
+
+	color.Blue("============ 品种持仓 =============")
+	for _, symbol := range portfolio.Symbols() {
+		account, _ := portfolio.Account(symbol)
+		if account.LastPrize == nil {
+			color.HiBlack("%s: 无行情数据", symbol)
+			continue
+		}
+		value := account.LastPrize.End * float64(account.Balance.StockVol)
+		color.HiBlack("%s: 最新报价=%.3f  持有份额=%d  持有市值=%.2f  持仓成本=%.2f  浮动盈亏=%.2f  已实现盈亏=%.2f  交易次数=%d",
+			symbol, account.LastPrize.End, account.Balance.StockVol, value, account.Balance.CostRMB, account.UnrealizedPnL(),
+			account.TradStat.RealizedPnL, account.TradStat.BuyCounter+account.TradStat.SellCounter)
+	}
+
+	color.Blue("============ 过程统计 =============")
+	color.HiBlack("交易次数=%d  (Buy=%d, Sell=%d)", t.SellCounter+t.BuyCounter, t.BuyCounter, t.SellCounter)
+	color.HiBlack("最高成本=%.2f  最低成本=%.2f", t.MaxCost, t.MinCost)
+	color.HiBlack("最高资产=%.2f  最低资产=%.2f", t.MaxValue, t.MinValue)
+	color.HiBlack("累计交易费用=%.2f", t.TotalFee)
+
+	color.Blue("============ 最终结果 =============")
+	color.HiBlack("组合总资产=%.2f", total)
+	color.HiBlack("可用余额=%.2f", portfolio.BalanceRMB)
+	color.HiBlack("持有市值=%.2f", portfolio.MarketValue())
+	color.HiBlack("已实现盈亏=%.2f", t.RealizedPnL)
+	color.HiBlack("总盈亏=%.2f  (%.2f%%)", total-portfolio.InitFundRMB, common.CountRiseRange(portfolio.InitFundRMB, total))
+	if t.CashInterest != 0 {
+		color.HiBlack("闲置现金收益=%.2f  交易盈亏=%.2f", t.CashInterest, total-portfolio.InitFundRMB-t.CashInterest)
+	}
+}
@@ -3,22 +3,97 @@ package handler
 import (
 	"fmt"
 	"github.com/BlackCarDriver/GoProject-api/color"
+	"github.com/BlackCarDriver/StockMaster/calendar"
 	"github.com/BlackCarDriver/StockMaster/common"
 	"github.com/BlackCarDriver/StockMaster/dao"
 	"github.com/BlackCarDriver/StockMaster/strategy"
 )
 
+// ValidateLevel 模拟前对k线数据的校验严格程度
+type ValidateLevel int
+
+const (
+	ValidateSkip   ValidateLevel = iota // 不校验
+	ValidateWarn                        // 校验并打印警告, 继续模拟
+	ValidateStrict                      // 数据存在问题时拒绝模拟
+)
+
+// SimulateOption 模拟过程的可选配置
+type SimulateOption struct {
+	Validate       ValidateLevel         // 数据校验严格程度
+	ValidateOption dao.ValidateOption    // 数据校验配置
+	AdjustFactors  []dao.AdjustFactor    // 复权因子, 设置后按不复权价格交易, 按复权价格计算收益
+	ExRights       []dao.CorporateAction // 除权除息事件, 在除权日把分红送转计入账号, 要求数据标记为不复权 (不能与AdjustFactors同时使用)
+	Sampling       common.EquitySampling // 资产曲线的采样频率, 不为SampleNone时覆盖账号的设置
+	Resume         bool                  // 从快照恢复的账号继续模拟, 跳过快照最新节点及之前的节点
+}
+
 // Simulate 根据指定账号状态和给出的k线图数据, 按照指定交易策略遍历指数数据, 得到最终的账号状态
 func Simulate(before common.Account, stockData dao.KLineData, strategy strategy.Strategy) (after *common.Account, err error) {
+	opt := SimulateOption{
+		Validate:       ValidateWarn,
+		ValidateOption: dao.ValidateOption{IsTradingDay: calendar.IsTradingDay},
+	}
+	return SimulateWithOption(before, stockData, strategy, opt)
+}
+
+// SimulateWithOption 按照指定配置执行模拟
+// opt.Resume为true时before需要是从快照恢复的账号, 从快照的最新节点之后继续模拟, 与不中断的模拟结果一致
+func SimulateWithOption(before common.Account, stockData dao.KLineData, strategy strategy.Strategy, opt SimulateOption) (after *common.Account, err error) {
 	account := &before
 	if before.InitFundRMB <= 0.0 || before.Name == "" || len(stockData.KLines) == 0 {
 		err = fmt.Errorf("unexpect params")
 		return
 	}
-	if account.Balance.BalanceRMB == 0 {
-		account.Balance.BalanceRMB = account.InitFundRMB
+	if opt.Resume && before.LastPrize == nil {
+		err = fmt.Errorf("unexpect params: Resume require account restored from snapshot")
+		return
+	}
+	if len(opt.AdjustFactors) > 0 && len(opt.ExRights) > 0 {
+		err = fmt.Errorf("unexpect params: AdjustFactors and ExRights can not be used together")
+		return
+	}
+	if len(opt.ExRights) > 0 && stockData.Adjust != dao.AdjustNone {
+		err = fmt.Errorf("unexpect params: ExRights require unadjusted data but got %s", stockData.Adjust)
+		return
+	}
+	if stockData, err = toTradData(stockData, opt.AdjustFactors); err != nil {
+		return
+	}
+	if err = validateKLine(stockData, opt); err != nil {
+		return
 	}
-	for i, moment := range stockData.KLines {
+	if opt.Sampling != common.SampleNone {
+		account.Sampling = opt.Sampling
+	}
+	if account.TargetName == "" { // 涨跌幅限制需要根据名称判断是否为ST股票
+		account.TargetName = stockData.Name
+	}
+	klines := stockData.KLines
+	prevFactor, exRightIdx := 0.0, 0
+	if opt.Resume { // 从快照恢复的账号: 跳过快照之前已经模拟过的节点, 继续模拟之后的节点
+		for len(klines) > 0 && klines[0].Timestamp <= account.LastPrize.Timestamp {
+			klines = klines[1:]
+		}
+		if len(klines) == 0 {
+			err = fmt.Errorf("unexpect params: no kline after snapshot, lastPrize=%s", account.LastPrize.TimeDesc)
+			return
+		}
+		prevFactor, exRightIdx = dao.GetFactor(opt.AdjustFactors, account.LastPrize.Timestamp), skipExRights(opt.ExRights, *account.LastPrize, true)
+	} else {
+		if account.Balance.BalanceRMB == 0 {
+			account.Balance.BalanceRMB = account.InitFundRMB
+		}
+		exRightIdx = skipExRights(opt.ExRights, klines[0], false)
+	}
+	for i, moment := range klines {
+		factor := dao.GetFactor(opt.AdjustFactors, moment.Timestamp)
+		account.UpdateAdjust(prevFactor, factor)
+		prevFactor = factor
+		for ; exRightIdx < len(opt.ExRights) && opt.ExRights[exRightIdx].ExDate <= dao.TradeDay(moment.TimeDesc); exRightIdx++ {
+			action := opt.ExRights[exRightIdx]
+			account.ApplyExRight(moment.Timestamp, action.Cash, action.Bonus)
+		}
 		account.UpdateStat(moment)
 
 		err = strategy.Execute(account, moment)
@@ -26,10 +101,48 @@ func Simulate(before common.Account, stockData dao.KLineData, strategy strategy.
 			log.Error("execute fail: i=%d err=%v moment=%+v", i, err, moment)
 			break
 		}
+		account.RecordEquity()
 	}
 	return account, err
 }
 
+// 按照配置的严格程度校验k线数据
+func validateKLine(stockData dao.KLineData, opt SimulateOption) (err error) {
+	if opt.Validate == ValidateSkip {
+		return
+	}
+	report := dao.ValidateKLineData(stockData, opt.ValidateOption)
+	if !report.IsValid() && opt.Validate == ValidateStrict {
+		return fmt.Errorf("invalid kline data: code=%s %s", stockData.Code, report.Summary())
+	}
+	if !report.IsValid() {
+		log.Warning("kline data has issues: code=%s %s", stockData.Code, report.Summary())
+	}
+	return
+}
+
+// 跳过模拟开始之前已经发生的除权除息事件, 除权日为第一个节点当天的事件需要处理
+// 从快照恢复时node为快照的最新节点, 当天的事件已经处理过, 需要同时跳过 (inclusive=true)
+func skipExRights(actions []dao.CorporateAction, node common.KLineNode, inclusive bool) (idx int) {
+	day := dao.TradeDay(node.TimeDesc)
+	for idx < len(actions) && (actions[idx].ExDate < day || (inclusive && actions[idx].ExDate == day)) {
+		idx++
+	}
+	return
+}
+
+// 交易使用不复权价格, 复权数据在提供复权因子时还原为不复权数据, 未标记复权方式的数据按复权数据处理
+func toTradData(stockData dao.KLineData, factors []dao.AdjustFactor) (tradData dao.KLineData, err error) {
+	if stockData.Adjust == dao.AdjustNone {
+		return stockData, nil
+	}
+	if len(factors) == 0 {
+		log.Warning("trading on %s adjusted prices, absolute price strategies may be unrealistic: code=%s", stockData.Adjust, stockData.Code)
+		return stockData, nil
+	}
+	return dao.ConvertAdjust(stockData, factors, dao.AdjustNone)
+}
+
 // PrintRunResult 在控制台打印模拟结果
 func PrintRunResult(account *common.Account, strategy strategy.Strategy, data dao.KLineData) {
 	if account == nil || account.LastPrize == nil {
@@ -41,13 +154,13 @@ func PrintRunResult(account *common.Account, strategy strategy.Strategy, data da
 	var buyList, sellList string
 	var buyCount, sellCount int
 	for _, item := range account.BuyEntrust {
-		if item.DealTime == 0 {
+		if item.IsOpen() {
 			buyCount++
 			buyList += fmt.Sprintf("%.2f, ", item.Price)
 		}
 	}
 	for _, item := range account.SellEntrust {
-		if item.DealTime == 0 {
+		if item.IsOpen() {
 			sellCount++
 			sellList += fmt.Sprintf("%.2f, ", item.Price)
 		}
@@ -56,6 +169,9 @@ func PrintRunResult(account *common.Account, strategy strategy.Strategy, data da
 	balance := account.Balance
 	canSell := float64(balance.StockVol) * account.LastPrize.End // 当前持有市值
 	currentValue := balance.BalanceRMB + canSell                 // 当前总资产
+	if account.Margin != nil {
+		currentValue = account.NetAsset() // 扣除融资融券负债
+	}
 
 	color.Blue("============ 数据描述 =============")
 	color.HiBlack("名称: %s  代码: %s", data.Name, data.Code)
@@ -78,7 +194,12 @@ func PrintRunResult(account *common.Account, strategy strategy.Strategy, data da
 
 	color.Blue("============ 交易记录 =============")
 	for i, item := range account.TradLog {
-		color.HiBlack("i=%d, %s, %s, 价格=%.2f   份额=%d ", i+1, common.TimeFormat(item.Timestamp), item.Mode, item.Prize, item.Vol)
+		pnlDesc := ""
+		if item.Mode == common.ModeShell {
+			pnlDesc = fmt.Sprintf("   实现盈亏=%.2f", item.RealizedPnL)
+		}
+		color.HiBlack("i=%d, %s, %s, 价格=%.3f   份额=%d   费用=%.2f   委托价=%.3f   委托份额=%d%s", i+1, common.TimeFormat(item.Timestamp),
+			item.Mode, item.Prize, item.Vol, item.Fee, item.EntrustPrize, item.EntrustVol, pnlDesc)
 	}
 
 	color.Blue("============ 委托列表 =============")
@@ -91,14 +212,32 @@ func PrintRunResult(account *common.Account, strategy strategy.Strategy, data da
 	color.HiBlack("最高持仓=%d    最低持仓=%d", t.MaxVol, t.MinVol)
 	color.HiBlack("最高成本=%.2f  最低成本=%.2f", t.MaxCost, t.MinCost)
 	color.HiBlack("最高资产=%.2f  最低资产=%.2f", t.MaxValue, t.MinValue)
+	color.HiBlack("累计交易费用=%.2f", t.TotalFee)
 
 	color.Blue("============ 最终结果 =============")
 	color.HiBlack("账户总资产=%.2f", currentValue)
 	color.HiBlack("可用余额=%.2f", balance.BalanceRMB)
 	color.HiBlack("最新报价=%.2f", account.LastPrize.End)
-	color.HiBlack("持有份额=%d", balance.StockVol)
-	color.HiBlack("持仓成本=%.2f", balance.CostRMB)
+	color.HiBlack("持有份额=%d  (可卖=%d)", balance.StockVol, account.SellableVol(account.LastPrize.Timestamp))
+	color.HiBlack("持仓成本=%.2f  持仓均价=%.3f", balance.CostRMB, account.AvgHoldPrice())
 	color.HiBlack("持有市值=%.2f", canSell)
-	color.HiBlack("持仓盈亏=%.2f  (%.2f%%)", canSell-balance.CostRMB, common.CountRiseRange(balance.CostRMB, canSell))
+	color.HiBlack("浮动盈亏=%.2f  (%.2f%%)", account.UnrealizedPnL(), common.CountRiseRange(balance.CostRMB, canSell))
+	color.HiBlack("已实现盈亏=%.2f", t.RealizedPnL)
+	if account.Margin != nil {
+		debt := account.Debt
+		color.HiBlack("融资负债=%.2f  融券份额=%d  融券冻结资金=%.2f  累计利息费用=%.2f", debt.FinanceDebt, debt.ShortVol, debt.ShortProceeds, t.MarginInterest)
+		color.HiBlack("净资产=%.2f  维持担保比例=%.3f", account.NetAsset(), account.MaintenanceRatio(account.LastPrize.End))
+	}
+	if t.DividendRMB != 0 || t.BonusVol != 0 {
+		color.HiBlack("累计分红=%.2f  累计送转份额=%d", t.DividendRMB, t.BonusVol)
+	}
 	color.HiBlack("总盈亏=%.2f  (%.2f%%)", currentValue-account.InitFundRMB, common.CountRiseRange(account.InitFundRMB, currentValue))
+	if t.CashInterest != 0 {
+		color.HiBlack("闲置现金收益=%.2f  交易盈亏=%.2f", t.CashInterest, currentValue-account.InitFundRMB-t.CashInterest)
+	}
+	if t.AdjustGain != 0 {
+		adjustValue := currentValue + t.AdjustGain // 按复权价格计算的总资产
+		color.HiBlack("除权补偿=%.2f", t.AdjustGain)
+		color.HiBlack("复权总盈亏=%.2f  (%.2f%%)", adjustValue-account.InitFundRMB, common.CountRiseRange(account.InitFundRMB, adjustValue))
+	}
 }
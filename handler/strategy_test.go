@@ -1,10 +1,15 @@
 package handler
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"github.com/BlackCarDriver/StockMaster/calendar"
 	"github.com/BlackCarDriver/StockMaster/common"
 	"github.com/BlackCarDriver/StockMaster/dao"
 	"github.com/BlackCarDriver/StockMaster/strategy"
+	"math"
+	"strings"
 	"testing"
 )
 
@@ -46,3 +51,451 @@ func TestGridStrategy(t *testing.T) {
 	log.Info("simulate success")
 	PrintRunResult(after, &gridStrategy1, mkData)
 }
+
+// 固定 510500 日线上网格策略的回测结果, 交易规则的改动导致结果变化时需要确认后更新
+func TestGridStrategyResult(t *testing.T) {
+	mkData, err := dao.ReadKLineMockData("../dao/mockdata/510500_1day.json")
+	if err != nil {
+		t.Fatalf("read fail: err=%v", err)
+	}
+	grid := gridStrategy1
+	after, err := Simulate(account1, mkData, &grid)
+	if err != nil {
+		t.Fatalf("simulate fail: err=%v", err)
+	}
+	s := after.TradStat
+	if s.BuyCounter != 494 || s.SellCounter != 364 || after.Balance.StockVol != 28800 ||
+		math.Abs(after.Balance.BalanceRMB-21057.4944) > 1e-3 || math.Abs(s.RealizedPnL-20645.8494) > 1e-3 {
+		t.Fatalf("unexpect grid result: buy=%d sell=%d vol=%d cash=%.4f pnl=%.4f",
+			s.BuyCounter, s.SellCounter, after.Balance.StockVol, after.Balance.BalanceRMB, s.RealizedPnL)
+	}
+}
+
+func TestSimulateValidate(t *testing.T) {
+	mkData, err := dao.ReadKLineMockData("../dao/mockdata/600036_1day.json")
+	if err != nil {
+		t.Fatalf("read fail: err=%v", err)
+	}
+	strategy := gridStrategy1
+	if _, err = SimulateWithOption(account1, mkData, &strategy, SimulateOption{Validate: ValidateStrict}); err == nil {
+		t.Fatalf("expect strict mode refuse negative price data")
+	}
+	if _, err = SimulateWithOption(account1, mkData, &strategy, SimulateOption{Validate: ValidateWarn}); err != nil {
+		t.Fatalf("expect warn mode continue: err=%v", err)
+	}
+}
+
+// 第一个节点按开盘价买入后一直持有
+type holdStrategy struct {
+	Vol int
+}
+
+func (h *holdStrategy) Execute(account *common.Account, moment common.KLineNode) (err error) {
+	if account.LastDeal == nil {
+		account.Trad(common.ModeBuy, moment.Start, h.Vol, moment)
+	}
+	return
+}
+
+func (h *holdStrategy) GetDesc() string {
+	return fmt.Sprintf("买入%d份后持有", h.Vol)
+}
+
+// 构造不复权数据, 第三个节点每股派息0.5元
+func mockRawKLine() dao.KLineData {
+	raw := dao.KLineData{Code: "600036", Name: "招商银行", Adjust: dao.AdjustNone, KLines: []common.KLineNode{
+		{TimeDesc: "2022-06-01", Start: 10.0, End: 10.0, Top: 10.1, Bottom: 9.9, PriceRise: 0},
+		{TimeDesc: "2022-06-02", Start: 10.0, End: 10.2, Top: 10.3, Bottom: 9.9, PriceRise: 0.2},
+		{TimeDesc: "2022-06-06", Start: 9.7, End: 9.9, Top: 10.0, Bottom: 9.6, PriceRise: 0.2},
+		{TimeDesc: "2022-06-07", Start: 9.9, End: 10.1, Top: 10.2, Bottom: 9.8, PriceRise: 0.2},
+	}}
+	for i := range raw.KLines {
+		raw.KLines[i].Timestamp, _ = dao.ParseKLineTime(raw.KLines[i].TimeDesc)
+	}
+	raw.ResetSummary()
+	return raw
+}
+
+func TestSimulateAdjust(t *testing.T) {
+	raw := mockRawKLine()
+	factors, err := dao.CalcAdjustFactors(raw)
+	if err != nil {
+		t.Fatalf("calc factors fail: err=%v", err)
+	}
+	backward, err := dao.ConvertAdjust(raw, factors, dao.AdjustBackward)
+	if err != nil {
+		t.Fatalf("convert fail: err=%v", err)
+	}
+
+	// 传入后复权数据, 按不复权价格成交, 分红计入除权补偿
+	after, err := SimulateWithOption(account1, backward, &holdStrategy{Vol: 1000}, SimulateOption{AdjustFactors: factors})
+	if err != nil {
+		t.Fatalf("simulate fail: err=%v", err)
+	}
+	if len(after.TradLog) != 1 || after.TradLog[0].Prize != 10.0 {
+		t.Fatalf("expect trade on unadjusted price: %+v", after.TradLog)
+	}
+	if math.Abs(after.TradStat.AdjustGain-500) > 0.01 {
+		t.Fatalf("unexpect adjust gain: %.4f", after.TradStat.AdjustGain)
+	}
+}
+
+func TestSimulateExRight(t *testing.T) {
+	raw := mockRawKLine()
+	exRights := []dao.CorporateAction{{ExDate: "2022-06-06", Cash: 0.5}}
+	after, err := SimulateWithOption(account1, raw, &holdStrategy{Vol: 1000}, SimulateOption{ExRights: exRights})
+	if err != nil {
+		t.Fatalf("simulate fail: err=%v", err)
+	}
+	b := after.Balance
+	if b.StockVol != 1000 || math.Abs(b.BalanceRMB-(account1.InitFundRMB-10000+500)) > 1e-6 || math.Abs(b.CostRMB-9500) > 1e-6 {
+		t.Fatalf("unexpect balance after dividend: %+v", b)
+	}
+	if n := len(after.ActionLog); n == 0 || after.ActionLog[n-1].Mode != common.ActionExRight {
+		t.Fatalf("expect ex-right action log: %+v", after.ActionLog)
+	}
+
+	// 除权日为第一个节点时, 开始模拟之前的持仓也要分红
+	holder := account1
+	holder.Balance = common.BalanceInfo{BalanceRMB: account1.InitFundRMB - 10000, StockVol: 1000, CostRMB: 10000}
+	after, err = SimulateWithOption(holder, raw, &holdStrategy{Vol: 0}, SimulateOption{ExRights: []dao.CorporateAction{{ExDate: "2022-06-01", Cash: 0.5}}})
+	if err != nil {
+		t.Fatalf("simulate fail: err=%v", err)
+	}
+	if after.TradStat.DividendRMB != 500 || math.Abs(after.Balance.CostRMB-9500) > 1e-6 {
+		t.Fatalf("expect dividend on first node: stat=%+v balance=%+v", after.TradStat, after.Balance)
+	}
+
+	// 送转股并调整委托价格, 调整后的价格按价格精度取整, 除权后仍可以成交
+	account := common.Account{Name: "ExRight", InitFundRMB: 10000, TargetStock: "600036", Balance: common.BalanceInfo{StockVol: 1000}}
+	account.CreateEntrust(common.ModeShell, 10.5, 100, raw.KLines[1].Timestamp, 0)
+	account.ApplyExRight(raw.KLines[2].Timestamp, 0, 0.3)
+	if account.Balance.StockVol != 1300 || account.SellEntrust[0].Price != 8.08 {
+		t.Fatalf("unexpect bonus result: vol=%d price=%.4f", account.Balance.StockVol, account.SellEntrust[0].Price)
+	}
+	exDay := common.KLineNode{Timestamp: raw.KLines[2].Timestamp, TimeDesc: "2022-06-06", Start: 8.1, End: 8.1, Top: 8.2, Bottom: 8.0}
+	account.UpdateStat(exDay)
+	if mode, _ := account.ExecuteEntrust(exDay); mode != common.ModeShell || account.Balance.StockVol != 1200 {
+		t.Fatalf("expect adjusted entrust filled: mode=%s vol=%d log=%+v", mode, account.Balance.StockVol, account.ActionLog)
+	}
+	if _, err = SimulateWithOption(account1, raw, &holdStrategy{Vol: 1000}, SimulateOption{ExRights: exRights, AdjustFactors: []dao.AdjustFactor{{}}}); err == nil {
+		t.Fatalf("expect error when ExRights and AdjustFactors both set")
+	}
+
+	// 未标记复权方式的数据按复权数据处理, 不能叠加除权除息事件
+	untagged := raw
+	untagged.Adjust = dao.AdjustUnknown
+	if _, err = SimulateWithOption(account1, untagged, &holdStrategy{Vol: 1000}, SimulateOption{ExRights: exRights}); err == nil {
+		t.Fatalf("expect error when ExRights used with untagged data")
+	}
+}
+
+func TestSimulateSTLimit(t *testing.T) {
+	// 按k线数据的名称识别ST股票, 涨跌幅限制为5%
+	raw := mockRawKLine()
+	raw.Name = "*ST招行"
+	account := account1
+	account.TargetStock = "600036"
+	after, err := Simulate(account, raw, &holdStrategy{Vol: 1000})
+	if err != nil {
+		t.Fatalf("simulate fail: err=%v", err)
+	}
+	if after.TargetName != raw.Name || after.Limit.PrevClose != 9.9 || after.Limit.Up != 10.4 || after.Limit.Down != 9.4 {
+		t.Fatalf("unexpect ST limit: name=%s limit=%+v", after.TargetName, after.Limit)
+	}
+}
+
+func TestSimulateFee(t *testing.T) {
+	account := account1
+	account.TargetStock, account.FeeModel = "600036", common.NewStandardFee()
+	after, err := SimulateWithOption(account, mockRawKLine(), &holdStrategy{Vol: 1000}, SimulateOption{})
+	if err != nil {
+		t.Fatalf("simulate fail: err=%v", err)
+	}
+	// 佣金不足5元按5元收取, 沪市股票另收过户费
+	if after.TradLog[0].Fee != 5.1 || after.TradStat.TotalFee != 5.1 || math.Abs(after.Balance.BalanceRMB-(account1.InitFundRMB-10005.1)) > 1e-6 {
+		t.Fatalf("unexpect fee: log=%+v stat=%+v balance=%+v", after.TradLog, after.TradStat, after.Balance)
+	}
+}
+
+func TestGridStrategyLock(t *testing.T) {
+	mkData, err := dao.ReadKLineMockData("../dao/mockdata/510500_1day.json")
+	if err != nil {
+		t.Fatalf("read fail: err=%v", err)
+	}
+	mkData.KLines = mkData.KLines[:500]
+	mkData.ResetSummary()
+
+	// 保留份额不足时不卖出, 持仓成本达到上限后不买入, 被禁止的操作记录到操作日志
+	grid := gridStrategy1
+	grid.FlowStepUp, grid.MaxCost, grid.MinRetain = 3, 30000, 2800
+	after, err := Simulate(account1, mkData, &grid)
+	if err != nil {
+		t.Fatalf("simulate fail: err=%v", err)
+	}
+	vol := 0
+	for i, record := range after.TradLog {
+		if record.Mode == common.ModeBuy {
+			vol += record.Vol
+		}
+		if record.Mode == common.ModeShell {
+			vol -= record.Vol
+		}
+		if i > 0 && vol < grid.MinRetain {
+			t.Fatalf("retain broken: i=%d vol=%d record=%+v", i, vol, record)
+		}
+	}
+	if after.TradStat.MaxCost > grid.MaxCost {
+		t.Fatalf("max cost broken: %.2f", after.TradStat.MaxCost)
+	}
+	locked := 0
+	for _, action := range after.ActionLog {
+		if action.Mode == common.ActionGiveUp && strings.Contains(action.Desc, "操作被禁止") {
+			locked++
+		}
+	}
+	if locked == 0 {
+		t.Fatalf("expect lock rejection logged")
+	}
+
+	// 不限制持仓成本时可以继续买入
+	grid.MaxCost = 0
+	unlimited, err := Simulate(account1, mkData, &grid)
+	if err != nil {
+		t.Fatalf("simulate fail: err=%v", err)
+	}
+	if unlimited.TradStat.BuyCounter <= after.TradStat.BuyCounter || unlimited.TradStat.MaxCost <= 30000 {
+		t.Fatalf("expect more buys without max cost: buy=%d maxCost=%.2f", unlimited.TradStat.BuyCounter, unlimited.TradStat.MaxCost)
+	}
+}
+
+func TestGridPositionCost(t *testing.T) {
+	// 网格交易的持仓成本不会因为盈利卖出而变为负数: 现金+持仓成本=初始资金+已实现盈亏
+	mkData, err := dao.ReadKLineMockData("../dao/mockdata/510500_1day.json")
+	if err != nil {
+		t.Fatalf("read fail: err=%v", err)
+	}
+	grid := gridStrategy1
+	after, err := Simulate(account1, mkData, &grid)
+	if err != nil {
+		t.Fatalf("simulate fail: err=%v", err)
+	}
+	total := 0.0
+	for _, record := range after.TradLog {
+		total += record.RealizedPnL
+	}
+	b := after.Balance
+	if b.CostRMB < 0 || after.TradStat.MinCost < 0 || math.Abs(total-after.TradStat.RealizedPnL) > 1e-3 ||
+		math.Abs(b.BalanceRMB+b.CostRMB-account1.InitFundRMB-after.TradStat.RealizedPnL) > 1e-3 {
+		t.Fatalf("unexpect grid position: balance=%+v realized=%.2f total=%.2f", b, after.TradStat.RealizedPnL, total)
+	}
+}
+
+func TestSimulateResume(t *testing.T) {
+	mkData, err := dao.ReadKLineMockData("../dao/mockdata/510500_1day.json")
+	if err != nil {
+		t.Fatalf("read fail: err=%v", err)
+	}
+	grid := gridStrategy1
+	grid.FlowStepUp, grid.MaxCost, grid.MinRetain, grid.ExpireDay = 3, 30000, 2800, 0
+	whole, err := Simulate(account1, mkData, &grid)
+	if err != nil {
+		t.Fatalf("simulate fail: err=%v", err)
+	}
+
+	// 前半段模拟后保存快照, 恢复后在包含前半段的完整数据上继续模拟, 已模拟的节点会被跳过
+	half := mkData
+	half.KLines = mkData.KLines[:len(mkData.KLines)/2]
+	first, err := Simulate(account1, half, &grid)
+	if err != nil {
+		t.Fatalf("simulate first half fail: err=%v", err)
+	}
+	var buf bytes.Buffer
+	if err = common.SaveAccount(&buf, first); err != nil {
+		t.Fatalf("save fail: err=%v", err)
+	}
+	restored, err := common.LoadAccount(&buf)
+	if err != nil {
+		t.Fatalf("load fail: err=%v", err)
+	}
+	if len(restored.OpenEntrusts()) == 0 || len(restored.OpenEntrusts()) != len(first.OpenEntrusts()) || restored.LastPrize == nil {
+		t.Fatalf("expect snapshot keep open entrusts and last price: %+v", restored.OpenEntrusts())
+	}
+	resumeOpt := SimulateOption{ValidateOption: dao.ValidateOption{IsTradingDay: calendar.IsTradingDay}, Resume: true}
+	resumed, err := SimulateWithOption(*restored, mkData, &grid, resumeOpt)
+	if err != nil {
+		t.Fatalf("simulate second half fail: err=%v", err)
+	}
+	wholeSnapshot, resumedSnapshot := whole.Snapshot(), resumed.Snapshot()
+	wholeSnapshot.SaveTime, resumedSnapshot.SaveTime = 0, 0
+	want, _ := json.Marshal(wholeSnapshot)
+	got, _ := json.Marshal(resumedSnapshot)
+	if string(want) != string(got) {
+		t.Fatalf("expect split run equal to unsplit run: whole=%+v resumed=%+v", whole.Balance, resumed.Balance)
+	}
+	if _, err = SimulateWithOption(*resumed, mkData, &grid, resumeOpt); err == nil {
+		t.Fatalf("expect error when no kline after snapshot")
+	}
+	if _, err = SimulateWithOption(account1, mkData, &grid, resumeOpt); err == nil {
+		t.Fatalf("expect error when resume without snapshot")
+	}
+	if _, err = Simulate(*resumed, mkData, &grid); err != nil {
+		t.Fatalf("expect simulate again without resume: err=%v", err)
+	}
+}
+
+func TestSimulatePortfolio(t *testing.T) {
+	// 两个品种使用网格策略, 总资产按各品种最新价格计算
+	var stockData []dao.KLineData
+	portfolio := common.NewPortfolio("Grid", 200000)
+	for _, code := range []string{"510500", "513050"} {
+		mkData, err := dao.ReadKLineMockData(fmt.Sprintf("../dao/mockdata/%s_1day.json", code))
+		if err != nil {
+			t.Fatalf("read fail: err=%v", err)
+		}
+		stockData = append(stockData, mkData)
+		portfolio.AddAccount(&common.Account{TargetStock: code})
+	}
+	grid := gridStrategy1
+	adapter := strategy.AdaptStrategy(&grid, "510500", "513050")
+	if err := SimulatePortfolio(portfolio, stockData, adapter, SimulateOption{Resume: true}); err == nil {
+		t.Fatalf("expect error when resume portfolio")
+	}
+	if err := SimulatePortfolio(portfolio, stockData, adapter, SimulateOption{}); err != nil {
+		t.Fatalf("simulate fail: err=%v", err)
+	}
+	realized, cost, buy := 0.0, 0.0, 0
+	for _, symbol := range portfolio.Symbols() {
+		account, _ := portfolio.Account(symbol)
+		if len(account.TradLog) == 0 || account.LastPrize.TimeDesc != stockData[0].KLines[len(stockData[0].KLines)-1].TimeDesc {
+			t.Fatalf("expect both symbols traded: symbol=%s trad=%d", symbol, len(account.TradLog))
+		}
+		realized, cost, buy = realized+account.TradStat.RealizedPnL, cost+account.Balance.CostRMB, buy+account.TradStat.BuyCounter
+	}
+	s := portfolio.TradStat
+	if s.BuyCounter != buy || math.Abs(s.RealizedPnL-realized) > 1e-6 || s.MinValue <= 0 || s.MaxValue < s.MinValue ||
+		math.Abs(portfolio.BalanceRMB+cost-portfolio.InitFundRMB-realized) > 1e-3 {
+		t.Fatalf("unexpect portfolio result: cash=%.2f cost=%.2f stat=%+v", portfolio.BalanceRMB, cost, s)
+	}
+	PrintPortfolioResult(portfolio, adapter)
+}
+
+func TestMarginGrid(t *testing.T) {
+	// 杠杆网格: 现金+持仓成本-融资负债=初始资金+已实现盈亏-利息
+	mkData, err := dao.ReadKLineMockData("../dao/mockdata/510500_1day.json")
+	if err != nil {
+		t.Fatalf("read fail: err=%v", err)
+	}
+	grid := gridStrategy1
+	grid.FlowStepUp, grid.MaxCost = 3, 60000
+	leveraged := account1
+	leveraged.InitFundRMB, leveraged.Margin = 30000, &common.MarginConfig{CollateralRatio: 1, FinanceRate: 0.06, MaintenanceRatio: 1.3}
+	after, err := Simulate(leveraged, mkData, &grid)
+	if err != nil {
+		t.Fatalf("simulate fail: err=%v", err)
+	}
+	b, s := after.Balance, after.TradStat
+	if s.MaxCost <= leveraged.InitFundRMB || s.MarginInterest <= 0 ||
+		math.Abs(b.BalanceRMB+b.CostRMB-after.Debt.FinanceDebt-leveraged.InitFundRMB-s.RealizedPnL+s.MarginInterest) > 1e-3 {
+		t.Fatalf("unexpect leveraged grid: balance=%+v debt=%+v stat=%+v", b, after.Debt, s)
+	}
+}
+
+func TestCashYieldGrid(t *testing.T) {
+	// 网格策略的闲置现金收益与交易盈亏分开统计
+	mkData, err := dao.ReadKLineMockData("../dao/mockdata/510500_1day.json")
+	if err != nil {
+		t.Fatalf("read fail: err=%v", err)
+	}
+	grid := gridStrategy1
+	yield := account1
+	yield.CashYield = &common.FixedYield{Rate: 0.02}
+	after, err := Simulate(yield, mkData, &grid)
+	if err != nil {
+		t.Fatalf("simulate fail: err=%v", err)
+	}
+	b, s := after.Balance, after.TradStat
+	if s.CashInterest <= 0 || math.Abs(b.BalanceRMB+b.CostRMB-yield.InitFundRMB-s.RealizedPnL-s.CashInterest) > 1e-3 {
+		t.Fatalf("unexpect grid yield: balance=%+v stat=%+v", b, s)
+	}
+}
+
+func TestEquityCurve(t *testing.T) {
+	mkData, err := dao.ReadKLineMockData("../dao/mockdata/510500_15min.json")
+	if err != nil {
+		t.Fatalf("read fail: err=%v", err)
+	}
+	grid := gridStrategy1
+	after, err := Simulate(account1, mkData, &grid)
+	if err != nil || len(after.Equity) != 0 {
+		t.Fatalf("expect no equity curve by default: err=%v n=%d", err, len(after.Equity))
+	}
+
+	// 按节点采样, 每个节点记录一次执行策略之后的状态
+	after, err = SimulateWithOption(account1, mkData, &grid, SimulateOption{Sampling: common.SampleBar})
+	if err != nil || len(after.Equity) != len(mkData.KLines) {
+		t.Fatalf("unexpect bar equity: err=%v n=%d", err, len(after.Equity))
+	}
+	for i, point := range after.Equity {
+		if point.Timestamp != mkData.KLines[i].Timestamp || math.Abs(point.Cash+point.MarketValue-point.TotalValue) > 1e-6 {
+			t.Fatalf("unexpect equity point: i=%d point=%+v", i, point)
+		}
+	}
+	last := after.Equity[len(after.Equity)-1]
+	if last.Cash != after.Balance.BalanceRMB || last.StockVol != after.Balance.StockVol || last.TotalValue != after.NetAsset() ||
+		last.BuyEntrust+last.SellEntrust != len(after.OpenEntrusts()) {
+		t.Fatalf("expect last point equal final state: point=%+v balance=%+v", last, after.Balance)
+	}
+
+	// 按日采样, 每个交易日保留最后一个节点的状态
+	days := make(map[string]bool)
+	for _, node := range mkData.KLines {
+		days[dao.TradeDay(node.TimeDesc)] = true
+	}
+	daily, err := SimulateWithOption(account1, mkData, &grid, SimulateOption{Sampling: common.SampleDay})
+	if err != nil || len(daily.Equity) != len(days) || daily.Equity[len(daily.Equity)-1] != last {
+		t.Fatalf("unexpect daily equity: err=%v n=%d days=%d", err, len(daily.Equity), len(days))
+	}
+
+	// 组合的资产曲线按共享现金和各品种市值计算
+	portfolio := common.NewPortfolio("Equity", 200000)
+	var stockData []dao.KLineData
+	for _, code := range []string{"510500", "513050"} {
+		data, err := dao.ReadKLineMockData(fmt.Sprintf("../dao/mockdata/%s_1day.json", code))
+		if err != nil {
+			t.Fatalf("read fail: err=%v", err)
+		}
+		stockData = append(stockData, data)
+		portfolio.AddAccount(&common.Account{TargetStock: code})
+	}
+	if err = SimulatePortfolio(portfolio, stockData, strategy.AdaptStrategy(&grid, "510500", "513050"), SimulateOption{Sampling: common.SampleDay}); err != nil {
+		t.Fatalf("simulate fail: err=%v", err)
+	}
+	if n := len(portfolio.Equity); n != len(stockData[0].KLines) || portfolio.Equity[n-1].TotalValue != portfolio.TotalValue() {
+		t.Fatalf("unexpect portfolio equity: n=%d", n)
+	}
+}
+
+func TestExpireByTradingDay(t *testing.T) {
+	mkData, err := dao.ReadKLineMockData("../dao/mockdata/510500_15min.json")
+	if err != nil {
+		t.Fatalf("read fail: err=%v", err)
+	}
+	// 模拟数据的时间戳按北京时间计算, 委托在下一个交易日收盘之后才失效
+	account := account1
+	account.Balance.BalanceRMB = account.InitFundRMB
+	first := mkData.KLines[0]
+	deadDay := calendar.AddTradingDays(calendar.InLocation(first.Timestamp), 1)
+	deadTime := calendar.DayClose(deadDay).Unix()
+	account.UpdateStat(first)
+	if _, err = account.CreateEntrust(common.ModeBuy, first.Bottom*0.95, 100, first.Timestamp, deadTime); err != nil {
+		t.Fatalf("create entrust fail: err=%v", err)
+	}
+	for _, node := range mkData.KLines[1:] {
+		account.UpdateStat(node)
+		account.ExecuteEntrustPath(node, nil)
+		if isOpen := len(account.OpenEntrusts()) > 0; isOpen != (dao.TradeDay(node.TimeDesc) <= deadDay.Format("2006-01-02")) {
+			t.Fatalf("unexpect entrust state at %s: isOpen=%v deadDay=%s", node.TimeDesc, isOpen, deadDay.Format("2006-01-02"))
+		}
+	}
+}
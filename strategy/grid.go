@@ -4,6 +4,7 @@ package strategy
 
 import (
 	"fmt"
+	"github.com/BlackCarDriver/StockMaster/calendar"
 	"github.com/BlackCarDriver/StockMaster/common"
 	"time"
 )
@@ -20,6 +21,8 @@ type GridStrategy struct {
 	MinRetain    int     `json:"minRetain"`    // 最低保留份额 (卖出时保证最少剩余多少份额)
 	Vol          int     `json:"vol"`          // 每次委托买入或卖出的数量
 	ExpireDay    int64   `json:"ExpireDay"`    // 委托条件单的有效天数
+
+	ExpireByTradingDay bool `json:"expireByTradingDay"` // ExpireDay按交易日计算 (在第N个交易日收盘后失效)
 }
 
 func (g *GridStrategy) Execute(account *common.Account, moment common.KLineNode) (err error) {
@@ -37,6 +40,10 @@ func (g *GridStrategy) Execute(account *common.Account, moment common.KLineNode)
 	if g.ExpireDay > 0 {
 		timeExpire = timeNow + 24*3600*g.ExpireDay
 	}
+	if g.ExpireDay > 0 && g.ExpireByTradingDay {
+		expireDay := calendar.AddTradingDays(calendar.InLocation(timeNow), int(g.ExpireDay))
+		timeExpire = calendar.DayClose(expireDay).Unix()
+	}
 
 	// 建仓
 	if account.LastDeal == nil {
@@ -44,53 +51,53 @@ func (g *GridStrategy) Execute(account *common.Account, moment common.KLineNode)
 			log.Debug("%s 未达到触发价", moment.TimeDesc)
 			return
 		}
-		isFirstDealOk, reason := false, ""
-		var dealPrize float64  // 成交价
-		if g.FirstPrize == 0 { // 到了指定时间, 按现价买入
-			dealPrize = moment.Start
-			isFirstDealOk, reason = account.Trad(common.ModeBuy, dealPrize, g.FirstVol, moment)
-		}
-		if g.FirstPrize > 0 && g.FirstPrize < moment.Top { // 跌破了触发价,以指定价格买入
-			dealPrize = g.FirstPrize
-			isFirstDealOk, reason = account.Trad(common.ModeBuy, dealPrize, g.FirstVol, moment)
+		firstVol := account.LegalVol(common.ModeBuy, g.FirstVol)
+		// 到了指定时间按现价买入, 跌破了触发价时以指定价格买入
+		dealPrize := account.LegalPrice(moment.Start)
+		if g.FirstPrize > 0 {
+			dealPrize = account.LegalPrice(g.FirstPrize)
 		}
-		if !isFirstDealOk {
-			log.Warning("create first deal fail: reason=%v", reason)
+		if dealErr := account.Trad(common.ModeBuy, dealPrize, firstVol, moment); dealErr != nil {
+			log.Warning("create first deal fail: err=%v", dealErr)
 			return
 		}
 		nextSellPrize := common.RisePrizeByFlow(dealPrize, g.FlowStepUp)
 		nextBuyPrize := common.RisePrizeByFlow(dealPrize, g.FlowStepDown)
-		account.CreateEntrust(common.ModeShell, nextSellPrize, g.Vol, timeNow, timeExpire)
-		account.CreateEntrust(common.ModeBuy, nextBuyPrize, g.Vol, timeNow, timeExpire)
+		account.CreateEntrust(common.ModeShell, nextSellPrize, account.LegalVol(common.ModeShell, g.Vol), timeNow, timeExpire)
+		account.CreateEntrust(common.ModeBuy, nextBuyPrize, account.LegalVol(common.ModeBuy, g.Vol), timeNow, timeExpire)
 		return
 	}
 
+	g.updateLock(account, moment.Start)
+
+	// 按节点内的价格路径依次执行触发的条件单, 每次成交后立即创建下一档委托
+	account.ExecuteEntrustPath(moment, func(mode common.OpMode, record common.Entrust) {
+		if record.Status != common.StatusFilled { // 部分成交时等待剩余份额成交后再创建下一档委托
+			return
+		}
+		nextSellPrize := common.RisePrizeByFlow(record.Price, g.FlowStepUp)
+		nextBuyPrize := common.RisePrizeByFlow(record.Price, g.FlowStepDown)
+		account.CreateEntrust(common.ModeShell, nextSellPrize, account.LegalVol(common.ModeShell, g.Vol), timeNow, timeExpire)
+		account.CreateEntrust(common.ModeBuy, nextBuyPrize, account.LegalVol(common.ModeBuy, g.Vol), timeNow, timeExpire)
+		g.updateLock(account, record.Price)
+	})
+	return
+}
+
+// 根据持仓成本和保留份额限制买入和卖出, MaxCost为0时不限制持仓成本
+func (g *GridStrategy) updateLock(account *common.Account, prize float64) {
 	account.Setting.BuyLock = false
 	account.Setting.SellLock = false
-	if account.Balance.CostRMB+moment.Start*float64(g.Vol) > g.MaxCost {
+	if g.MaxCost > 0 && account.Balance.CostRMB+prize*float64(g.Vol) > g.MaxCost {
 		account.Setting.BuyLock = true
 	}
 	if account.Balance.StockVol-g.Vol < g.MinRetain {
 		account.Setting.SellLock = true
 	}
-
-	// 等待条件单触发
-	mode, record := account.ExecuteEntrust(moment)
-	if mode == common.ModeWait {
-		return
-	}
-	if mode == common.ModeBuy || mode == common.ModeShell {
-		nextSellPrize := common.RisePrizeByFlow(record.Price, g.FlowStepUp)
-		nextBuyPrize := common.RisePrizeByFlow(record.Price, g.FlowStepDown)
-		account.CreateEntrust(common.ModeShell, nextSellPrize, g.Vol, timeNow, timeExpire)
-		account.CreateEntrust(common.ModeBuy, nextBuyPrize, g.Vol, timeNow, timeExpire)
-	}
-
-	return
 }
 
 func (g *GridStrategy) GetDesc() (desc string) {
-	startTime, endTime, firstPrize, maxCost := "不限制", "不限制", "不限制", "不限制"
+	startTime, endTime, firstPrize, maxCost, expire := "不限制", "不限制", "不限制", "不限制", "不限制"
 	if g.StartTime > 0 {
 		startTime = time.Unix(g.StartTime, 0).Format("2006-01-02 15:04")
 	}
@@ -103,6 +110,12 @@ func (g *GridStrategy) GetDesc() (desc string) {
 	if g.MaxCost > 0 {
 		maxCost = fmt.Sprintf("%.2f", g.MaxCost)
 	}
-	return fmt.Sprintf("生效时间=[%s~%s] \n建仓价格=%s 建仓交易额=%d \n买入跌幅=%.2f%% 卖出涨幅=%.2f%% \n保留额度=%d 限制市值=%s \n",
-		startTime, endTime, firstPrize, g.FirstVol, -g.FlowStepDown, g.FlowStepUp, g.MinRetain, maxCost)
+	if g.ExpireDay > 0 {
+		expire = fmt.Sprintf("%d天", g.ExpireDay)
+	}
+	if g.ExpireDay > 0 && g.ExpireByTradingDay {
+		expire = fmt.Sprintf("%d个交易日", g.ExpireDay)
+	}
+	return fmt.Sprintf("生效时间=[%s~%s] \n建仓价格=%s 建仓交易额=%d \n买入跌幅=%.2f%% 卖出涨幅=%.2f%% \n保留额度=%d 限制市值=%s \n委托有效期=%s \n",
+		startTime, endTime, firstPrize, g.FirstVol, -g.FlowStepDown, g.FlowStepUp, g.MinRetain, maxCost, expire)
 }
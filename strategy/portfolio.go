@@ -0,0 +1,51 @@
+package strategy
+
+import (
+	"fmt"
+	"github.com/BlackCarDriver/StockMaster/common"
+	"sort"
+)
+
+// PortfolioStrategy 组合交易策略_接口描述, 每个节点附带所属品种的代码
+type PortfolioStrategy interface {
+	Execute(portfolio *common.Portfolio, symbol string, stock common.KLineNode) (err error) // 根据组合状况和品种的最新指数执行策略
+	GetDesc() string                                                                        // 获取策略的具体行为描述
+}
+
+// StrategyAdapter 把单品种策略适配为组合策略, 每个品种的节点交给该品种的策略处理, 策略操作的是组合中该品种的子账号
+type StrategyAdapter struct {
+	Strategies map[string]Strategy // 各品种使用的策略, key为品种代码, 没有策略的品种不做操作
+}
+
+// AdaptStrategy 多个品种使用同一个单品种策略, 策略需要是无状态的 (状态只保存在账号中, 例如GridStrategy)
+func AdaptStrategy(strategy Strategy, symbols ...string) *StrategyAdapter {
+	adapter := &StrategyAdapter{Strategies: make(map[string]Strategy)}
+	for _, symbol := range symbols {
+		adapter.Strategies[symbol] = strategy
+	}
+	return adapter
+}
+
+func (s *StrategyAdapter) Execute(portfolio *common.Portfolio, symbol string, stock common.KLineNode) (err error) {
+	strategy, isExist := s.Strategies[symbol]
+	if !isExist {
+		return
+	}
+	account, isExist := portfolio.Account(symbol)
+	if !isExist {
+		return fmt.Errorf("unexpect symbol: symbol=%s", symbol)
+	}
+	return strategy.Execute(account, stock)
+}
+
+func (s *StrategyAdapter) GetDesc() (desc string) {
+	var symbols []string
+	for symbol := range s.Strategies {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	for _, symbol := range symbols {
+		desc += fmt.Sprintf("[%s] \n%s", symbol, s.Strategies[symbol].GetDesc())
+	}
+	return
+}